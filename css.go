@@ -0,0 +1,508 @@
+package yoga
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// cssEnums maps the CSS properties that take a keyword to the entries of
+// styleEnums that hold them.
+var cssEnums = map[string]string{
+	"direction":       "direction",
+	"writing-mode":    "writingMode",
+	"flex-direction":  "flexDirection",
+	"justify-content": "justifyContent",
+	"align-content":   "alignContent",
+	"align-items":     "alignItems",
+	"align-self":      "alignSelf",
+	"position":        "positionType",
+	"flex-wrap":       "flexWrap",
+	"overflow":        "overflow",
+	"display":         "display",
+}
+
+// cssKeywordAliases maps CSS keywords to the names of the enum values they
+// stand for when the two differ.
+var cssKeywordAliases = map[string]string{
+	"nowrap":   "no-wrap",
+	"baseline": "base-line",
+}
+
+// cssValueKinds tells which kinds of values besides points a property takes,
+// and whether it rejects negative values.
+type cssValueKinds int
+
+const (
+	cssPercent cssValueKinds = 1 << iota
+	cssAuto
+	cssNonNegative
+)
+
+var cssValues = map[string]struct {
+	name  string
+	kinds cssValueKinds
+}{
+	"flex-basis": {"flexBasis", cssPercent | cssAuto},
+	"width":      {"width", cssPercent | cssAuto},
+	"height":     {"height", cssPercent | cssAuto},
+	"min-width":  {"minWidth", cssPercent},
+	"min-height": {"minHeight", cssPercent},
+	"max-width":  {"maxWidth", cssPercent},
+	"max-height": {"maxHeight", cssPercent},
+}
+
+// cssEdgeProperties maps the shorthands for the edges of a box, which expand
+// to EdgeAll, EdgeVertical and EdgeHorizontal, to the entries of styleEdges
+// that hold them. The longhands are named by cssEdgeLonghand.
+var cssEdgeProperties = map[string]struct {
+	name  string
+	kinds cssValueKinds
+}{
+	"margin":       {"margin", cssPercent | cssAuto},
+	"padding":      {"padding", cssPercent | cssNonNegative},
+	"border-width": {"border", cssNonNegative},
+	"inset":        {"position", cssPercent},
+}
+
+var cssEdgeNames = map[Edge]string{
+	EdgeLeft:       "left",
+	EdgeTop:        "top",
+	EdgeRight:      "right",
+	EdgeBottom:     "bottom",
+	EdgeStart:      "inline-start",
+	EdgeEnd:        "inline-end",
+	EdgeBlockStart: "block-start",
+	EdgeBlockEnd:   "block-end",
+}
+
+// cssEdgeLonghand returns the name of the longhand of shorthand for edge, e.g.
+// margin-left, border-left-width or inset-inline-start. The physical insets
+// are named left, top, right and bottom.
+func cssEdgeLonghand(shorthand string, edge Edge) string {
+	switch shorthand {
+	case "border-width":
+		return "border-" + cssEdgeNames[edge] + "-width"
+	case "inset":
+		if edge <= EdgeBottom {
+			return cssEdgeNames[edge]
+		}
+	}
+	return shorthand + "-" + cssEdgeNames[edge]
+}
+
+type cssToken struct {
+	text   string
+	offset int
+}
+
+type cssParser struct {
+	src string
+}
+
+// errorf returns an error for the text at offset in the source.
+func (p *cssParser) errorf(offset int, format string, args ...interface{}) error {
+	line := 1 + strings.Count(p.src[:offset], "\n")
+	column := offset - strings.LastIndex(p.src[:offset], "\n")
+	return fmt.Errorf("%s at line %d, column %d", fmt.Sprintf(format, args...), line, column)
+}
+
+// ApplyCSS sets the style of node from decl, a list of CSS declarations
+// separated by semicolons such as "flex-direction: row; padding: 8px 16px".
+// Lengths are points, with or without a px suffix, or percentages. The margin,
+// padding, border-width and inset shorthands set EdgeAll, EdgeVertical and
+// EdgeHorizontal and clear the other edges, and flex sets flexGrow,
+// flexShrink and flexBasis as in CSS. Errors give the line and column of the
+// offending text, and node is left unchanged when there is one.
+func ApplyCSS(node *Node, decl string) error {
+	p := &cssParser{src: stripCSSComments(decl)}
+	var changes []func(node *Node)
+	start := 0
+	for start <= len(p.src) {
+		end := strings.IndexByte(p.src[start:], ';')
+		if end < 0 {
+			end = len(p.src)
+		} else {
+			end += start
+		}
+		if strings.TrimSpace(p.src[start:end]) != "" {
+			change, err := p.declaration(start, end)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+		start = end + 1
+	}
+	for _, change := range changes {
+		change(node)
+	}
+	return nil
+}
+
+// stripCSSComments replaces comments with spaces, keeping the offsets and
+// lines of the rest of src.
+func stripCSSComments(src string) string {
+	b := []byte(src)
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] != '/' || b[i+1] != '*' {
+			continue
+		}
+		end := strings.Index(src[i+2:], "*/")
+		if end < 0 {
+			end = len(b)
+		} else {
+			end += i + 4
+		}
+		for j := i; j < end; j++ {
+			if b[j] != '\n' {
+				b[j] = ' '
+			}
+		}
+		i = end - 1
+	}
+	return string(b)
+}
+
+// tokens splits the text between start and end at white space and slashes.
+func (p *cssParser) tokens(start, end int) []cssToken {
+	var tokens []cssToken
+	for i := start; i < end; {
+		switch c := p.src[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '/':
+			tokens = append(tokens, cssToken{text: "/", offset: i})
+			i++
+		default:
+			j := i
+			for j < end && !strings.ContainsRune(" \t\n\r\f/", rune(p.src[j])) {
+				j++
+			}
+			tokens = append(tokens, cssToken{text: p.src[i:j], offset: i})
+			i = j
+		}
+	}
+	return tokens
+}
+
+// declaration parses the declaration between start and end and returns the
+// change it makes to a node.
+func (p *cssParser) declaration(start, end int) (func(node *Node), error) {
+	colon := strings.IndexByte(p.src[start:end], ':')
+	nameTokens := p.tokens(start, end)
+	if colon < 0 {
+		return nil, p.errorf(nameTokens[0].offset, "Expected ':' after %q", nameTokens[0].text)
+	}
+	colon += start
+	nameTokens = p.tokens(start, colon)
+	if len(nameTokens) == 0 {
+		return nil, p.errorf(colon, "Missing property name")
+	}
+	if len(nameTokens) > 1 {
+		return nil, p.errorf(nameTokens[1].offset, "Unexpected %q after property name", nameTokens[1].text)
+	}
+	name := cssToken{text: strings.ToLower(nameTokens[0].text), offset: nameTokens[0].offset}
+	values := p.tokens(colon+1, end)
+	if len(values) == 0 {
+		return nil, p.errorf(colon, "Missing value for %q", name.text)
+	}
+
+	if enum, ok := cssEnums[name.text]; ok {
+		return p.enumDeclaration(name, enum, values)
+	}
+	if property, ok := cssValues[name.text]; ok {
+		value, err := p.singleValue(name, values, property.kinds)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range styleValues {
+			if v.name == property.name {
+				field := v.field
+				return func(node *Node) { setStyleValue(node, field(&node.style), value) }, nil
+			}
+		}
+	}
+	for shorthand, property := range cssEdgeProperties {
+		if name.text == shorthand {
+			return p.edgesDeclaration(name, property.name, property.kinds, values)
+		}
+		for edge := range cssEdgeNames {
+			if name.text == cssEdgeLonghand(shorthand, edge) {
+				value, err := p.singleValue(name, values, property.kinds)
+				if err != nil {
+					return nil, err
+				}
+				field := styleEdgesField(property.name)
+				edge := edge
+				return func(node *Node) { setStyleValue(node, &field(&node.style)[edge], value) }, nil
+			}
+		}
+	}
+
+	switch name.text {
+	case "flex":
+		return p.flexDeclaration(name, values)
+	case "flex-grow", "flex-shrink":
+		number, err := p.singleNumber(name, values)
+		if err != nil {
+			return nil, err
+		}
+		if name.text == "flex-grow" {
+			return func(node *Node) { SetFlexGrow(node, number) }, nil
+		}
+		return func(node *Node) { SetFlexShrink(node, number) }, nil
+	case "aspect-ratio":
+		return p.aspectRatioDeclaration(name, values)
+	case "gap":
+		if len(values) > 2 {
+			return nil, p.errorf(values[2].offset, "Too many values for %q", name.text)
+		}
+		gaps := make([]Value, len(values))
+		for i, token := range values {
+			var err error
+			if gaps[i], err = p.value(name, token, cssPercent); err != nil {
+				return nil, err
+			}
+		}
+		return func(node *Node) {
+			undefined := Value{value: math.NaN(), unit: UnitUndefined}
+			if len(gaps) == 1 {
+				setStyleValue(node, &node.style.gap[GutterAll], gaps[0])
+				setStyleValue(node, &node.style.gap[GutterRow], undefined)
+				setStyleValue(node, &node.style.gap[GutterColumn], undefined)
+				return
+			}
+			setStyleValue(node, &node.style.gap[GutterAll], undefined)
+			setStyleValue(node, &node.style.gap[GutterRow], gaps[0])
+			setStyleValue(node, &node.style.gap[GutterColumn], gaps[1])
+		}, nil
+	case "row-gap", "column-gap":
+		value, err := p.singleValue(name, values, cssPercent)
+		if err != nil {
+			return nil, err
+		}
+		gutter := GutterRow
+		if name.text == "column-gap" {
+			gutter = GutterColumn
+		}
+		return func(node *Node) { setStyleValue(node, &node.style.gap[gutter], value) }, nil
+	}
+	return nil, p.errorf(name.offset, "Unknown property %q", name.text)
+}
+
+func (p *cssParser) enumDeclaration(name cssToken, enumName string, values []cssToken) (func(node *Node), error) {
+	if len(values) > 1 {
+		return nil, p.errorf(values[1].offset, "Too many values for %q", name.text)
+	}
+	keyword := strings.ToLower(values[0].text)
+	if alias, ok := cssKeywordAliases[keyword]; ok {
+		keyword = alias
+	}
+	for _, enum := range styleEnums {
+		if enum.name != enumName {
+			continue
+		}
+		for i := 0; i < enum.count; i++ {
+			if enum.names(i) == keyword {
+				enum, i := enum, i
+				return func(node *Node) {
+					if enum.get(&node.style) != i {
+						enum.set(&node.style, i)
+						MarkDirtyInternal(node)
+					}
+				}, nil
+			}
+		}
+	}
+	return nil, p.errorf(values[0].offset, "Invalid value %q for %q", values[0].text, name.text)
+}
+
+// edgesDeclaration expands a shorthand with one to four values the way CSS
+// does: one value sets all edges, two the vertical and horizontal edges,
+// three the top, horizontal and bottom edges, and four the top, right,
+// bottom and left edges.
+func (p *cssParser) edgesDeclaration(name cssToken, fieldName string, kinds cssValueKinds,
+	values []cssToken) (func(node *Node), error) {
+	if len(values) > 4 {
+		return nil, p.errorf(values[4].offset, "Too many values for %q", name.text)
+	}
+	parsed := make([]Value, len(values))
+	for i, token := range values {
+		var err error
+		if parsed[i], err = p.value(name, token, kinds); err != nil {
+			return nil, err
+		}
+	}
+	var edges [11]Value
+	for edge := range edges {
+		edges[edge] = Value{value: math.NaN(), unit: UnitUndefined}
+	}
+	switch len(parsed) {
+	case 1:
+		edges[EdgeAll] = parsed[0]
+	case 2:
+		edges[EdgeVertical] = parsed[0]
+		edges[EdgeHorizontal] = parsed[1]
+	case 3:
+		edges[EdgeTop] = parsed[0]
+		edges[EdgeHorizontal] = parsed[1]
+		edges[EdgeBottom] = parsed[2]
+	case 4:
+		edges[EdgeTop] = parsed[0]
+		edges[EdgeRight] = parsed[1]
+		edges[EdgeBottom] = parsed[2]
+		edges[EdgeLeft] = parsed[3]
+	}
+	field := styleEdgesField(fieldName)
+	return func(node *Node) {
+		for edge := range edges {
+			setStyleValue(node, &field(&node.style)[edge], edges[edge])
+		}
+	}, nil
+}
+
+// flexDeclaration expands the flex shorthand as CSS does, so that a single
+// number n stands for "n 1 0" rather than for the flex style property.
+func (p *cssParser) flexDeclaration(name cssToken, values []cssToken) (func(node *Node), error) {
+	grow, shrink := 1.0, 1.0
+	basis := Value{value: 0, unit: UnitPixel}
+	switch keyword := strings.ToLower(values[0].text); {
+	case len(values) == 1 && keyword == "none":
+		grow, shrink, basis = 0, 0, Value{value: math.NaN(), unit: UnitAuto}
+	case len(values) == 1 && keyword == "auto":
+		basis = Value{value: math.NaN(), unit: UnitAuto}
+	case len(values) == 1 && keyword == "initial":
+		grow, basis = 0, Value{value: math.NaN(), unit: UnitAuto}
+	case len(values) > 3:
+		return nil, p.errorf(values[3].offset, "Too many values for %q", name.text)
+	default:
+		rest := values
+		if _, err := strconv.ParseFloat(values[0].text, 64); err == nil {
+			if grow, err = p.number(name, values[0]); err != nil {
+				return nil, err
+			}
+			rest = rest[1:]
+			if len(rest) > 0 {
+				if _, err := strconv.ParseFloat(rest[0].text, 64); err == nil {
+					if shrink, err = p.number(name, rest[0]); err != nil {
+						return nil, err
+					}
+					rest = rest[1:]
+				}
+			}
+		}
+		if len(rest) > 1 {
+			return nil, p.errorf(rest[1].offset, "Invalid value %q for %q", rest[1].text, name.text)
+		}
+		if len(rest) == 1 {
+			// Unlike other lengths, the basis needs a unit unless it is 0.
+			if number, err := strconv.ParseFloat(rest[0].text, 64); err == nil && number != 0 {
+				return nil, p.errorf(rest[0].offset, "Invalid value %q for %q", rest[0].text, name.text)
+			}
+			var err error
+			if basis, err = p.value(name, rest[0], cssPercent|cssAuto); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return func(node *Node) {
+		if !math.IsNaN(node.style.flex) {
+			SetFlex(node, math.NaN())
+		}
+		SetFlexGrow(node, grow)
+		SetFlexShrink(node, shrink)
+		setStyleValue(node, &node.style.flexBasis, basis)
+	}, nil
+}
+
+// aspectRatioDeclaration accepts a number, a ratio such as 16 / 9, or auto.
+func (p *cssParser) aspectRatioDeclaration(name cssToken, values []cssToken) (func(node *Node), error) {
+	aspectRatio := math.NaN()
+	switch {
+	case len(values) == 1 && strings.ToLower(values[0].text) == "auto":
+	case len(values) == 1:
+		number, err := p.number(name, values[0])
+		if err != nil {
+			return nil, err
+		}
+		aspectRatio = number
+	case len(values) == 3 && values[1].text == "/":
+		width, err := p.number(name, values[0])
+		if err != nil {
+			return nil, err
+		}
+		height, err := p.number(name, values[2])
+		if err != nil {
+			return nil, err
+		}
+		aspectRatio = width / height
+	default:
+		return nil, p.errorf(values[0].offset, "Invalid value for %q", name.text)
+	}
+	return func(node *Node) { SetAspectRatio(node, aspectRatio) }, nil
+}
+
+func (p *cssParser) singleValue(name cssToken, values []cssToken, kinds cssValueKinds) (Value, error) {
+	if len(values) > 1 {
+		return Value{}, p.errorf(values[1].offset, "Too many values for %q", name.text)
+	}
+	return p.value(name, values[0], kinds)
+}
+
+func (p *cssParser) singleNumber(name cssToken, values []cssToken) (float64, error) {
+	if len(values) > 1 {
+		return 0, p.errorf(values[1].offset, "Too many values for %q", name.text)
+	}
+	return p.number(name, values[0])
+}
+
+func (p *cssParser) number(name cssToken, token cssToken) (float64, error) {
+	number, err := strconv.ParseFloat(token.text, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) || number < 0 {
+		return 0, p.errorf(token.offset, "Invalid value %q for %q", token.text, name.text)
+	}
+	return number, nil
+}
+
+// value parses a length in points, with or without a px suffix, or, if kinds
+// allows them, a percentage or auto. Negative values are rejected if kinds
+// includes cssNonNegative.
+func (p *cssParser) value(name cssToken, token cssToken, kinds cssValueKinds) (Value, error) {
+	text := strings.ToLower(token.text)
+	unit := UnitPixel
+	switch {
+	case text == "auto" && kinds&cssAuto != 0:
+		return Value{value: math.NaN(), unit: UnitAuto}, nil
+	case strings.HasSuffix(text, "%") && kinds&cssPercent != 0:
+		text = strings.TrimSuffix(text, "%")
+		unit = UnitPercent
+	default:
+		text = strings.TrimSuffix(text, "px")
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) || (number < 0 && kinds&cssNonNegative != 0) {
+		return Value{}, p.errorf(token.offset, "Invalid value %q for %q", token.text, name.text)
+	}
+	return Value{value: number, unit: unit}, nil
+}
+
+func styleEdgesField(name string) func(style *Style) *[11]Value {
+	for _, edges := range styleEdges {
+		if edges.name == name {
+			return edges.field
+		}
+	}
+	return nil
+}
+
+// setStyleValue sets field, a Value in the style of node, marking node dirty
+// if it changes.
+func setStyleValue(node *Node, field *Value, value Value) {
+	if field.unit == value.unit && (value.unit == UnitUndefined || value.unit == UnitAuto || field.value == value.value) {
+		return
+	}
+	*field = value
+	MarkDirtyInternal(node)
+}
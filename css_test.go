@@ -0,0 +1,175 @@
+package yoga
+
+import (
+	"math"
+	"testing"
+)
+
+// valueEqual is like ValueEqual but also treats auto values as equal.
+func valueEqual(a, b Value) bool {
+	if a.unit == UnitAuto {
+		return b.unit == UnitAuto
+	}
+	return ValueEqual(a, b)
+}
+
+func TestApplyCSSFlexRejectsNegativeFactors(t *testing.T) {
+	for _, test := range []struct {
+		decl string
+		err  string
+	}{
+		{"flex: -1", `Invalid value "-1" for "flex" at line 1, column 7`},
+		{"flex: 1 -2", `Invalid value "-2" for "flex" at line 1, column 9`},
+		{"flex: -1 1 10px", `Invalid value "-1" for "flex" at line 1, column 7`},
+		{"flex-grow: -1", `Invalid value "-1" for "flex-grow" at line 1, column 12`},
+	} {
+		node := NewNode()
+		err := ApplyCSS(node, test.decl)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("ApplyCSS(%q) = %v, want %s", test.decl, err, test.err)
+		}
+		if GetFlexGrow(node) != 0 || GetFlexShrink(node) != 0 {
+			t.Errorf("ApplyCSS(%q) changed the node after an error", test.decl)
+		}
+	}
+}
+
+func TestApplyCSS(t *testing.T) {
+	node := NewNode()
+	err := ApplyCSS(node, `flex-direction: row; padding: 8px 16px; width: 50%;
+		margin-left: auto; flex: 1 0 auto; /* a comment; with a semicolon */
+		Align-Items: BASELINE; flex-wrap: nowrap; gap: 4 5%; aspect-ratio: 16 / 8;
+		border-width: 1 2 3; inset-inline-start: 7; min-height: 10%;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetFlexDirection(node) != FlexDirectionRow || GetAlignItems(node) != AlignBaseLine || GetFlexWrap(node) != WrapNoWrap {
+		t.Error("keywords were not applied")
+	}
+	for _, test := range []struct {
+		name string
+		got  Value
+		want Value
+	}{
+		{"padding vertical", node.style.padding[EdgeVertical], Value{8, UnitPixel}},
+		{"padding horizontal", node.style.padding[EdgeHorizontal], Value{16, UnitPixel}},
+		{"width", GetStyleWidth(node), Value{50, UnitPercent}},
+		{"flex basis", GetFlexBasis(node), Value{math.NaN(), UnitAuto}},
+		{"row gap", GetGap(node, GutterRow), Value{4, UnitPixel}},
+		{"column gap", GetGap(node, GutterColumn), Value{5, UnitPercent}},
+		{"border top", node.style.border[EdgeTop], Value{1, UnitPixel}},
+		{"border horizontal", node.style.border[EdgeHorizontal], Value{2, UnitPixel}},
+		{"border bottom", node.style.border[EdgeBottom], Value{3, UnitPixel}},
+		{"inset start", node.style.position[EdgeStart], Value{7, UnitPixel}},
+		{"min height", GetMinHeight(node), Value{10, UnitPercent}},
+	} {
+		if !valueEqual(test.got, test.want) {
+			t.Errorf("%s = %v, want %v", test.name, test.got, test.want)
+		}
+	}
+	if margin, _ := GetMargin(node, EdgeLeft); margin.unit != UnitAuto {
+		t.Errorf("margin left = %v, want auto", margin)
+	}
+	if GetFlexGrow(node) != 1 || GetFlexShrink(node) != 0 || GetAspectRatio(node) != 2 {
+		t.Errorf("flex grow, shrink and aspect ratio = %v, %v, %v, want 1, 0, 2",
+			GetFlexGrow(node), GetFlexShrink(node), GetAspectRatio(node))
+	}
+}
+
+func TestApplyCSSShorthandsClearEdges(t *testing.T) {
+	node := NewNode()
+	if err := ApplyCSS(node, "margin-top: 5; margin-inline-end: 6; margin: 1 2 3 4"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[Edge]float64{EdgeTop: 1, EdgeRight: 2, EdgeBottom: 3, EdgeLeft: 4}
+	for edge := EdgeLeft; edge <= EdgeBlockEnd; edge++ {
+		got := node.style.margin[edge]
+		if value, ok := want[edge]; ok {
+			if !ValueEqual(got, Value{value, UnitPixel}) {
+				t.Errorf("margin %v = %v, want %v", edge, got, value)
+			}
+		} else if got.unit != UnitUndefined {
+			t.Errorf("margin %v = %v, want undefined", edge, got)
+		}
+	}
+
+	if err := ApplyCSS(node, "margin: 9"); err != nil {
+		t.Fatal(err)
+	}
+	if margin, _ := GetMargin(node, EdgeTop); margin.value != 9 {
+		t.Errorf("margin top after margin: 9 = %v, want 9", margin)
+	}
+}
+
+func TestApplyCSSFlexShorthand(t *testing.T) {
+	for _, test := range []struct {
+		decl         string
+		grow, shrink float64
+		basis        Value
+	}{
+		{"flex: 2", 2, 1, Value{0, UnitPixel}},
+		{"flex: 2 3", 2, 3, Value{0, UnitPixel}},
+		{"flex: 10%", 1, 1, Value{10, UnitPercent}},
+		{"flex: 1 30px", 1, 1, Value{30, UnitPixel}},
+		{"flex: 1 2 0", 1, 2, Value{0, UnitPixel}},
+		{"flex: auto", 1, 1, Value{math.NaN(), UnitAuto}},
+		{"flex: none", 0, 0, Value{math.NaN(), UnitAuto}},
+		{"flex: initial", 0, 1, Value{math.NaN(), UnitAuto}},
+	} {
+		node := NewNode()
+		if err := ApplyCSS(node, test.decl); err != nil {
+			t.Errorf("ApplyCSS(%q): %v", test.decl, err)
+			continue
+		}
+		if GetFlexGrow(node) != test.grow || GetFlexShrink(node) != test.shrink || !valueEqual(GetFlexBasis(node), test.basis) {
+			t.Errorf("ApplyCSS(%q) set %v %v %v, want %v %v %v", test.decl,
+				GetFlexGrow(node), GetFlexShrink(node), GetFlexBasis(node), test.grow, test.shrink, test.basis)
+		}
+	}
+}
+
+func TestApplyCSSErrors(t *testing.T) {
+	for _, test := range []struct {
+		decl string
+		err  string
+	}{
+		{"colour: red", `Unknown property "colour" at line 1, column 1`},
+		{"width 10", `Expected ':' after "width" at line 1, column 1`},
+		{": 10", `Missing property name at line 1, column 1`},
+		{"min width: 10", `Unexpected "width" after property name at line 1, column 5`},
+		{"width:", `Missing value for "width" at line 1, column 6`},
+		{"width: 10 20", `Too many values for "width" at line 1, column 11`},
+		{"width: wide", `Invalid value "wide" for "width" at line 1, column 8`},
+		{"min-width: auto", `Invalid value "auto" for "min-width" at line 1, column 12`},
+		{"border-width: 10%", `Invalid value "10%" for "border-width" at line 1, column 15`},
+		{"margin: 1 2 3 4 5", `Too many values for "margin" at line 1, column 17`},
+		{"display: grid", `Invalid value "grid" for "display" at line 1, column 10`},
+		{"display: flex none", `Too many values for "display" at line 1, column 15`},
+		{"flex: 1 1 1px 1", `Too many values for "flex" at line 1, column 15`},
+		{"flex: 1 auto 2", `Invalid value "2" for "flex" at line 1, column 14`},
+		{"flex-grow: 1 2", `Too many values for "flex-grow" at line 1, column 14`},
+		{"aspect-ratio: 16 9", `Invalid value for "aspect-ratio" at line 1, column 15`},
+		{"aspect-ratio: 16 / -9", `Invalid value "-9" for "aspect-ratio" at line 1, column 20`},
+		{"padding: -5px", `Invalid value "-5px" for "padding" at line 1, column 10`},
+		{"padding: 1 -2%", `Invalid value "-2%" for "padding" at line 1, column 12`},
+		{"padding-inline-start: -1", `Invalid value "-1" for "padding-inline-start" at line 1, column 23`},
+		{"border-width: 1 -1", `Invalid value "-1" for "border-width" at line 1, column 17`},
+		{"border-top-width: -3px", `Invalid value "-3px" for "border-top-width" at line 1, column 19`},
+		{"flex: 1 1 2", `Invalid value "2" for "flex" at line 1, column 11`},
+		{"flex: 2 3 4", `Invalid value "4" for "flex" at line 1, column 11`},
+		{"gap: 1 2 3", `Too many values for "gap" at line 1, column 10`},
+		{"width: 10;\n  /* comment */ height: 5;\n  padding: x", `Invalid value "x" for "padding" at line 3, column 12`},
+	} {
+		node := NewNode()
+		if err := ApplyCSS(node, test.decl); err == nil || err.Error() != test.err {
+			t.Errorf("ApplyCSS(%q) = %v, want %s", test.decl, err, test.err)
+		}
+		// Declarations before the failing one are not applied either.
+		if err := ApplyCSS(node, "flex-direction: row; "+test.decl); err == nil {
+			t.Errorf("ApplyCSS(%q) succeeded", test.decl)
+		}
+		if GetFlexDirection(node) != FlexDirectionColumn {
+			t.Errorf("ApplyCSS(%q) changed the node after an error", test.decl)
+		}
+	}
+}
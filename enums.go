@@ -8,6 +8,18 @@ const (
 	DirectionRTL
 )
 
+func (d Direction) String() string {
+	switch d {
+	case DirectionInherit:
+		return "inherit"
+	case DirectionLTR:
+		return "ltr"
+	case DirectionRTL:
+		return "rtl"
+	}
+	return ""
+}
+
 type MeasureMode int
 
 const (
@@ -47,6 +59,7 @@ const (
 	JustifyFlexEnd
 	JustifySpaceBetween
 	JustifySpaceAround
+	JustifySpaceEvenly
 )
 
 func (j Justify) String() string {
@@ -59,6 +72,8 @@ func (j Justify) String() string {
 		return "space-between"
 	case JustifySpaceAround:
 		return "space-around"
+	case JustifySpaceEvenly:
+		return "space-evenly"
 	case JustifyFlexStart:
 		return "flex-start"
 	}
@@ -74,6 +89,9 @@ const (
 	AlignFlexEnd
 	AlignStretch
 	AlignBaseLine
+	AlignSpaceBetween
+	AlignSpaceAround
+	AlignSpaceEvenly
 )
 
 func (a Align) String() string {
@@ -86,6 +104,12 @@ func (a Align) String() string {
 		return "stretch"
 	case AlignBaseLine:
 		return "base-line"
+	case AlignSpaceBetween:
+		return "space-between"
+	case AlignSpaceAround:
+		return "space-around"
+	case AlignSpaceEvenly:
+		return "space-evenly"
 	case AlignFlexStart:
 		return "flex-start"
 	case AlignAuto:
@@ -99,6 +123,7 @@ type PositionType int
 const (
 	PositionTypeRelative PositionType = iota
 	PositionTypeAbsolute
+	PositionTypeStatic
 )
 
 func (p PositionType) String() string {
@@ -107,6 +132,8 @@ func (p PositionType) String() string {
 		return "relative"
 	case PositionTypeAbsolute:
 		return "absolute"
+	case PositionTypeStatic:
+		return "static"
 	}
 	return ""
 }
@@ -116,8 +143,21 @@ type Wrap int
 const (
 	WrapNoWrap Wrap = iota
 	WrapWrap
+	WrapWrapReverse
 )
 
+func (w Wrap) String() string {
+	switch w {
+	case WrapNoWrap:
+		return "no-wrap"
+	case WrapWrap:
+		return "wrap"
+	case WrapWrapReverse:
+		return "wrap-reverse"
+	}
+	return ""
+}
+
 type Overflow int
 
 const (
@@ -138,12 +178,53 @@ func (o Overflow) String() string {
 	return ""
 }
 
+type Display int
+
+const (
+	DisplayFlex Display = iota
+	DisplayNone
+	DisplayContents
+)
+
+func (d Display) String() string {
+	switch d {
+	case DisplayFlex:
+		return "flex"
+	case DisplayNone:
+		return "none"
+	case DisplayContents:
+		return "contents"
+	}
+	return ""
+}
+
+type WritingMode int
+
+const (
+	WritingModeHorizontalTB WritingMode = iota
+	WritingModeVerticalRL
+	WritingModeVerticalLR
+)
+
+func (w WritingMode) String() string {
+	switch w {
+	case WritingModeHorizontalTB:
+		return "horizontal-tb"
+	case WritingModeVerticalRL:
+		return "vertical-rl"
+	case WritingModeVerticalLR:
+		return "vertical-lr"
+	}
+	return ""
+}
+
 type Unit int
 
 const (
 	UnitUndefined Unit = iota
 	UnitPixel
 	UnitPercent
+	UnitAuto
 )
 
 func (u Unit) String() string {
@@ -152,6 +233,8 @@ func (u Unit) String() string {
 		return "px"
 	case UnitPercent:
 		return "%"
+	case UnitAuto:
+		return "auto"
 	}
 	return ""
 }
@@ -168,8 +251,58 @@ const (
 	EdgeHorizontal
 	EdgeVertical
 	EdgeAll
+	EdgeBlockStart
+	EdgeBlockEnd
+)
+
+func (e Edge) String() string {
+	switch e {
+	case EdgeLeft:
+		return "left"
+	case EdgeTop:
+		return "top"
+	case EdgeRight:
+		return "right"
+	case EdgeBottom:
+		return "bottom"
+	case EdgeStart:
+		return "start"
+	case EdgeEnd:
+		return "end"
+	case EdgeHorizontal:
+		return "horizontal"
+	case EdgeVertical:
+		return "vertical"
+	case EdgeAll:
+		return "all"
+	case EdgeBlockStart:
+		return "block-start"
+	case EdgeBlockEnd:
+		return "block-end"
+	}
+	return ""
+}
+
+type Gutter int
+
+const (
+	GutterColumn Gutter = iota
+	GutterRow
+	GutterAll
 )
 
+func (g Gutter) String() string {
+	switch g {
+	case GutterColumn:
+		return "column"
+	case GutterRow:
+		return "row"
+	case GutterAll:
+		return "all"
+	}
+	return ""
+}
+
 type Dimension int
 
 const (
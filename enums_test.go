@@ -0,0 +1,79 @@
+package yoga
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testStrings(t *testing.T, want map[fmt.Stringer]string) {
+	t.Helper()
+	for value, name := range want {
+		if got := value.String(); got != name {
+			t.Errorf("%T(%d).String() = %q, want %q", value, value, got, name)
+		}
+	}
+}
+
+func TestWrapString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		WrapNoWrap:      "no-wrap",
+		WrapWrap:        "wrap",
+		WrapWrapReverse: "wrap-reverse",
+	})
+}
+
+func TestGutterString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		GutterColumn: "column",
+		GutterRow:    "row",
+		GutterAll:    "all",
+	})
+}
+
+func TestDisplayString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		DisplayFlex:     "flex",
+		DisplayNone:     "none",
+		DisplayContents: "contents",
+	})
+}
+
+func TestJustifyAndAlignString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		JustifyFlexStart:    "flex-start",
+		JustifySpaceBetween: "space-between",
+		JustifySpaceAround:  "space-around",
+		JustifySpaceEvenly:  "space-evenly",
+		AlignAuto:           "auto",
+		AlignBaseLine:       "base-line",
+		AlignSpaceBetween:   "space-between",
+		AlignSpaceAround:    "space-around",
+		AlignSpaceEvenly:    "space-evenly",
+	})
+}
+
+func TestPositionTypeString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		PositionTypeStatic:   "static",
+		PositionTypeRelative: "relative",
+		PositionTypeAbsolute: "absolute",
+	})
+}
+
+func TestWritingModeString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		WritingModeHorizontalTB: "horizontal-tb",
+		WritingModeVerticalRL:   "vertical-rl",
+		WritingModeVerticalLR:   "vertical-lr",
+	})
+}
+
+func TestEdgeString(t *testing.T) {
+	testStrings(t, map[fmt.Stringer]string{
+		EdgeLeft:       "left",
+		EdgeStart:      "start",
+		EdgeAll:        "all",
+		EdgeBlockStart: "block-start",
+		EdgeBlockEnd:   "block-end",
+	})
+}
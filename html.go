@@ -0,0 +1,180 @@
+package yoga
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+// htmlStyleSheet makes divs behave like new nodes, which differ from the
+// defaults of CSS in their display, flex direction, shrinking, minimum size
+// and box sizing.
+const htmlStyleSheet = `body {
+  margin: 0;
+}
+div {
+  box-sizing: border-box;
+  display: flex;
+  position: relative;
+  flex-direction: column;
+  align-items: stretch;
+  align-content: flex-start;
+  flex-shrink: 0;
+  min-width: 0;
+  min-height: 0;
+  margin: 0;
+  padding: 0;
+  border: 0 solid black;
+}
+`
+
+// htmlEdgeOrder lists the edges of margin, padding, border and position,
+// shorthands first so that the more specific edges override them in CSS as
+// they do in ComputedEdgeValue and ResolvedEdgeValue.
+var htmlEdgeOrder = []Edge{EdgeAll, EdgeHorizontal, EdgeVertical, EdgeLeft, EdgeTop, EdgeRight, EdgeBottom,
+	EdgeStart, EdgeEnd, EdgeBlockStart, EdgeBlockEnd}
+
+// htmlEdgeProperties returns the CSS properties that set edge of shorthand.
+func htmlEdgeProperties(shorthand string, edge Edge) []string {
+	switch edge {
+	case EdgeAll:
+		return []string{shorthand}
+	case EdgeHorizontal:
+		return []string{cssEdgeLonghand(shorthand, EdgeLeft), cssEdgeLonghand(shorthand, EdgeRight)}
+	case EdgeVertical:
+		return []string{cssEdgeLonghand(shorthand, EdgeTop), cssEdgeLonghand(shorthand, EdgeBottom)}
+	}
+	return []string{cssEdgeLonghand(shorthand, edge)}
+}
+
+// ExportHTML writes root and its descendants to w as a standalone HTML
+// document of nested divs whose inline styles are the CSS equivalent of the
+// style of each node, so that the layout a browser computes can be compared
+// to that of CalculateLayout. The computed layout of each node is given in
+// the title of its div. Nodes with a measure function, whose content is not
+// known, and a root without a size are given the size computed for them.
+func ExportHTML(root *Node, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Layout</title>\n")
+	fmt.Fprintf(bw, "<style>\n%s</style>\n</head>\n<body>\n", htmlStyleSheet)
+	exportHTMLNode(bw, root, 0)
+	fmt.Fprintf(bw, "</body>\n</html>\n")
+	return bw.Flush()
+}
+
+func exportHTMLNode(w io.Writer, node *Node, level int) {
+	indent := strings.Repeat("  ", level)
+	title := fmt.Sprintf("left: %s, top: %s, width: %s, height: %s",
+		formatNumber(node.layout.position[EdgeLeft]), formatNumber(node.layout.position[EdgeTop]),
+		formatNumber(node.layout.dimensions[DimensionWidth]), formatNumber(node.layout.dimensions[DimensionHeight]))
+	fmt.Fprintf(w, "%s<div style=\"%s\" title=\"%s\">", indent,
+		html.EscapeString(strings.Join(htmlStyle(node), "; ")), html.EscapeString(title))
+	if len(node.children) == 0 {
+		fmt.Fprintf(w, "</div>\n")
+		return
+	}
+	fmt.Fprintf(w, "\n")
+	for _, child := range node.children {
+		exportHTMLNode(w, child, level+1)
+	}
+	fmt.Fprintf(w, "%s</div>\n", indent)
+}
+
+// htmlValue formats value as a CSS length, percentage or auto.
+func htmlValue(value *Value) string {
+	switch value.unit {
+	case UnitPixel:
+		return formatNumber(value.value) + "px"
+	case UnitPercent:
+		return formatNumber(value.value) + "%"
+	case UnitAuto:
+		return "auto"
+	}
+	return ""
+}
+
+// htmlStyle returns the CSS declarations equivalent to the style of node.
+func htmlStyle(node *Node) []string {
+	style := &node.style
+	var decls []string
+	add := func(property, value string) {
+		decls = append(decls, property+": "+value)
+	}
+	addValue := func(property string, value *Value) {
+		if value.unit != UnitUndefined && (value.unit == UnitAuto || !math.IsNaN(value.value)) {
+			add(property, htmlValue(value))
+		}
+	}
+	htmlKeyword := func(name string) string {
+		for keyword, alias := range cssKeywordAliases {
+			if alias == name {
+				return keyword
+			}
+		}
+		return name
+	}
+
+	if style.display != DisplayFlex {
+		add("display", style.display.String())
+	}
+	if style.direction != DirectionInherit {
+		add("direction", style.direction.String())
+	}
+	if style.writingMode != WritingModeHorizontalTB {
+		add("writing-mode", style.writingMode.String())
+	}
+	add("flex-direction", style.flexDirection.String())
+	add("justify-content", style.justifyContent.String())
+	add("align-items", htmlKeyword(style.alignItems.String()))
+	add("align-self", htmlKeyword(style.alignSelf.String()))
+	add("align-content", htmlKeyword(style.alignContent.String()))
+	add("flex-wrap", htmlKeyword(style.flexWrap.String()))
+	add("overflow", style.overflow.String())
+	add("position", style.positionType.String())
+	add("flex-grow", formatNumber(GetFlexGrow(node)))
+	add("flex-shrink", formatNumber(GetFlexShrink(node)))
+	addValue("flex-basis", GetFlexBasisPtr(node))
+
+	width, height := style.dimensions[DimensionWidth], style.dimensions[DimensionHeight]
+	if node.measure != nil || node.parent == nil {
+		if width.unit == UnitUndefined && !math.IsNaN(node.layout.dimensions[DimensionWidth]) {
+			width = Value{value: node.layout.dimensions[DimensionWidth], unit: UnitPixel}
+		}
+		if height.unit == UnitUndefined && !math.IsNaN(node.layout.dimensions[DimensionHeight]) {
+			height = Value{value: node.layout.dimensions[DimensionHeight], unit: UnitPixel}
+		}
+	}
+	addValue("width", &width)
+	addValue("height", &height)
+	addValue("min-width", &style.minDimensions[DimensionWidth])
+	addValue("min-height", &style.minDimensions[DimensionHeight])
+	addValue("max-width", &style.maxDimensions[DimensionWidth])
+	addValue("max-height", &style.maxDimensions[DimensionHeight])
+	if !math.IsNaN(style.aspectRatio) {
+		add("aspect-ratio", formatNumber(style.aspectRatio))
+	}
+
+	for _, property := range []struct {
+		shorthand string
+		edges     *[11]Value
+	}{
+		{"margin", &style.margin},
+		{"padding", &style.padding},
+		{"border-width", &style.border},
+		{"inset", &style.position},
+	} {
+		for _, edge := range htmlEdgeOrder {
+			for _, name := range htmlEdgeProperties(property.shorthand, edge) {
+				addValue(name, &property.edges[edge])
+			}
+		}
+	}
+
+	addValue("gap", &style.gap[GutterAll])
+	addValue("row-gap", &style.gap[GutterRow])
+	addValue("column-gap", &style.gap[GutterColumn])
+	return decls
+}
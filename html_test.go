@@ -0,0 +1,101 @@
+package yoga
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func exportHTML(t *testing.T, root *Node) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ExportHTML(root, &buf); err != nil {
+		t.Fatalf("ExportHTML: %v", err)
+	}
+	return buf.String()
+}
+
+func TestExportHTML(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetPadding(root, EdgeAll, 5)
+	SetPadding(root, EdgeLeft, 10)
+	child := newSizedChild(root, 20, 30)
+	SetMarginAuto(child, EdgeStart)
+	leaf := NewNode()
+	SetMeasureFunc(leaf, func(node *Node, width float64, widthMode MeasureMode, height float64, heightMode MeasureMode) Size {
+		return Size{width: 12, height: 8}
+	})
+	InsertChild(root, leaf, 1)
+	calculateLayout(t, root)
+
+	out := exportHTML(t, root)
+	if !strings.HasPrefix(out, "<!DOCTYPE html>\n") || !strings.HasSuffix(out, "</div>\n</body>\n</html>\n") {
+		t.Errorf("output is not a complete document:\n%s", out)
+	}
+	if !strings.Contains(out, "<style>\n"+htmlStyleSheet+"</style>") {
+		t.Errorf("output does not contain the style sheet:\n%s", out)
+	}
+	for _, want := range []string{
+		// The root is given its computed size and shorthands come first.
+		`<div style="flex-direction: row; `,
+		`width: 47px; height: 40px; padding: 5px; padding-left: 10px" title="left: 0, top: 0, width: 47, height: 40">` + "\n",
+		`  <div style="flex-direction: column; `,
+		`width: 20px; height: 30px; margin-inline-start: auto" title="left: 10, top: 5, width: 20, height: 30"></div>`,
+		// The measured leaf is given its computed size.
+		`flex-shrink: 0; width: 12px; height: 30px" title="left: 30, top: 5, width: 12, height: 30"></div>`,
+		"\n</div>\n</body>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %s:\n%s", want, out)
+		}
+	}
+	body := out[strings.Index(out, "<body>"):]
+	for _, unwanted := range []string{"display:", " direction:", "writing-mode:", "min-width:", "aspect-ratio:", "gap:"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("output contains the default %s:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestExportHTMLStyle(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	child := NewNode()
+	SetDisplay(child, DisplayNone)
+	SetDirection(child, DirectionRTL)
+	SetWritingMode(child, WritingModeVerticalRL)
+	SetAlignItems(child, AlignBaseLine)
+	SetFlexWrap(child, WrapWrapReverse)
+	SetPositionType(child, PositionTypeAbsolute)
+	SetFlexGrow(child, 1.5)
+	SetFlexBasisPercent(child, 25)
+	SetMinWidthPercent(child, 10)
+	SetMaxHeight(child, 80)
+	SetAspectRatio(child, 0.5)
+	SetBorder(child, EdgeVertical, 2)
+	SetPosition(child, EdgeEnd, 4)
+	SetGap(child, GutterColumn, 3)
+	InsertChild(root, child, 0)
+
+	// Nodes other than the root and measured nodes keep their style size.
+	out := exportHTML(t, root)
+	for _, want := range []string{
+		"display: none; direction: rtl; writing-mode: vertical-rl; ",
+		"align-items: baseline; ",
+		"flex-wrap: wrap-reverse; ",
+		"position: absolute; flex-grow: 1.5; flex-shrink: 0; flex-basis: 25%; min-width: 10%; max-height: 80px; aspect-ratio: 0.5; ",
+		"border-top-width: 2px; border-bottom-width: 2px; inset-inline-end: 4px; column-gap: 3px\"",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportHTMLReturnsWriteErrors(t *testing.T) {
+	if err := ExportHTML(NewNode(), failingWriter{}); err == nil || err.Error() != "write failed" {
+		t.Errorf("ExportHTML = %v, want the error of the writer", err)
+	}
+}
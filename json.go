@@ -0,0 +1,537 @@
+package yoga
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// styleEnums describes the enum properties of Style. They are encoded as the
+// names returned by their String methods.
+var styleEnums = []struct {
+	name  string
+	count int
+	names func(i int) string
+	get   func(style *Style) int
+	set   func(style *Style, i int)
+}{
+	{"direction", int(DirectionRTL) + 1,
+		func(i int) string { return Direction(i).String() },
+		func(style *Style) int { return int(style.direction) },
+		func(style *Style, i int) { style.direction = Direction(i) }},
+	{"writingMode", int(WritingModeVerticalLR) + 1,
+		func(i int) string { return WritingMode(i).String() },
+		func(style *Style) int { return int(style.writingMode) },
+		func(style *Style, i int) { style.writingMode = WritingMode(i) }},
+	{"flexDirection", int(FlexDirectionRowReverse) + 1,
+		func(i int) string { return FlexDirection(i).String() },
+		func(style *Style) int { return int(style.flexDirection) },
+		func(style *Style, i int) { style.flexDirection = FlexDirection(i) }},
+	{"justifyContent", int(JustifySpaceEvenly) + 1,
+		func(i int) string { return Justify(i).String() },
+		func(style *Style) int { return int(style.justifyContent) },
+		func(style *Style, i int) { style.justifyContent = Justify(i) }},
+	{"alignContent", int(AlignSpaceEvenly) + 1,
+		func(i int) string { return Align(i).String() },
+		func(style *Style) int { return int(style.alignContent) },
+		func(style *Style, i int) { style.alignContent = Align(i) }},
+	{"alignItems", int(AlignSpaceEvenly) + 1,
+		func(i int) string { return Align(i).String() },
+		func(style *Style) int { return int(style.alignItems) },
+		func(style *Style, i int) { style.alignItems = Align(i) }},
+	{"alignSelf", int(AlignSpaceEvenly) + 1,
+		func(i int) string { return Align(i).String() },
+		func(style *Style) int { return int(style.alignSelf) },
+		func(style *Style, i int) { style.alignSelf = Align(i) }},
+	{"positionType", int(PositionTypeStatic) + 1,
+		func(i int) string { return PositionType(i).String() },
+		func(style *Style) int { return int(style.positionType) },
+		func(style *Style, i int) { style.positionType = PositionType(i) }},
+	{"flexWrap", int(WrapWrapReverse) + 1,
+		func(i int) string { return Wrap(i).String() },
+		func(style *Style) int { return int(style.flexWrap) },
+		func(style *Style, i int) { style.flexWrap = Wrap(i) }},
+	{"overflow", int(OverflowScroll) + 1,
+		func(i int) string { return Overflow(i).String() },
+		func(style *Style) int { return int(style.overflow) },
+		func(style *Style, i int) { style.overflow = Overflow(i) }},
+	{"display", int(DisplayContents) + 1,
+		func(i int) string { return Display(i).String() },
+		func(style *Style) int { return int(style.display) },
+		func(style *Style, i int) { style.display = Display(i) }},
+}
+
+// styleNumbers describes the number properties of Style, which are left out
+// when undefined.
+var styleNumbers = []struct {
+	name  string
+	field func(style *Style) *float64
+}{
+	{"flex", func(style *Style) *float64 { return &style.flex }},
+	{"flexGrow", func(style *Style) *float64 { return &style.flexGrow }},
+	{"flexShrink", func(style *Style) *float64 { return &style.flexShrink }},
+	{"aspectRatio", func(style *Style) *float64 { return &style.aspectRatio }},
+}
+
+// styleValues describes the Value properties of Style, which are left out
+// when undefined.
+var styleValues = []struct {
+	name  string
+	field func(style *Style) *Value
+}{
+	{"flexBasis", func(style *Style) *Value { return &style.flexBasis }},
+	{"width", func(style *Style) *Value { return &style.dimensions[DimensionWidth] }},
+	{"height", func(style *Style) *Value { return &style.dimensions[DimensionHeight] }},
+	{"minWidth", func(style *Style) *Value { return &style.minDimensions[DimensionWidth] }},
+	{"minHeight", func(style *Style) *Value { return &style.minDimensions[DimensionHeight] }},
+	{"maxWidth", func(style *Style) *Value { return &style.maxDimensions[DimensionWidth] }},
+	{"maxHeight", func(style *Style) *Value { return &style.maxDimensions[DimensionHeight] }},
+}
+
+// styleEdges describes the per-edge properties of Style. They are encoded as
+// objects keyed by the names of the edges that are defined.
+var styleEdges = []struct {
+	name  string
+	field func(style *Style) *[11]Value
+}{
+	{"margin", func(style *Style) *[11]Value { return &style.margin }},
+	{"position", func(style *Style) *[11]Value { return &style.position }},
+	{"padding", func(style *Style) *[11]Value { return &style.padding }},
+	{"border", func(style *Style) *[11]Value { return &style.border }},
+}
+
+// MarshalTree encodes root and all of its descendants as JSON. Only the style
+// properties that differ from those of a new node are included.
+func MarshalTree(root *Node) ([]byte, error) {
+	return json.MarshalIndent(marshalNode(root, false), "", "  ")
+}
+
+// MarshalTreeWithLayout is like MarshalTree but also includes the computed
+// layout of every node.
+func MarshalTreeWithLayout(root *Node) ([]byte, error) {
+	return json.MarshalIndent(marshalNode(root, true), "", "  ")
+}
+
+func marshalNode(node *Node, withLayout bool) map[string]interface{} {
+	object := map[string]interface{}{"style": marshalStyle(&node.style)}
+	if withLayout {
+		object["layout"] = marshalLayout(node)
+	}
+	if len(node.children) > 0 {
+		children := make([]interface{}, len(node.children))
+		for i, child := range node.children {
+			children[i] = marshalNode(child, withLayout)
+		}
+		object["children"] = children
+	}
+	return object
+}
+
+func marshalStyle(style *Style) map[string]interface{} {
+	var defaultNode Node
+	nodeInit(&defaultNode)
+
+	object := map[string]interface{}{}
+	for _, enum := range styleEnums {
+		if enum.get(style) != enum.get(&defaultNode.style) {
+			object[enum.name] = enum.names(enum.get(style))
+		}
+	}
+	for _, number := range styleNumbers {
+		if value := *number.field(style); !math.IsNaN(value) {
+			object[number.name] = value
+		}
+	}
+	for _, value := range styleValues {
+		if v, ok := marshalValue(value.field(style)); ok {
+			object[value.name] = v
+		}
+	}
+	for _, edges := range styleEdges {
+		values := map[string]interface{}{}
+		for edge := EdgeLeft; edge <= EdgeBlockEnd; edge++ {
+			if v, ok := marshalValue(&edges.field(style)[edge]); ok {
+				values[edge.String()] = v
+			}
+		}
+		if len(values) > 0 {
+			object[edges.name] = values
+		}
+	}
+	gap := map[string]interface{}{}
+	for gutter := GutterColumn; gutter <= GutterAll; gutter++ {
+		if v, ok := marshalValue(&style.gap[gutter]); ok {
+			gap[gutter.String()] = v
+		}
+	}
+	if len(gap) > 0 {
+		object["gap"] = gap
+	}
+	return object
+}
+
+// marshalValue encodes points as numbers and percentages and auto as strings.
+// It reports false for undefined values.
+func marshalValue(value *Value) (interface{}, bool) {
+	switch value.unit {
+	case UnitPixel:
+		if math.IsNaN(value.value) {
+			return nil, false
+		}
+		return value.value, true
+	case UnitPercent:
+		if math.IsNaN(value.value) {
+			return nil, false
+		}
+		return strconv.FormatFloat(value.value, 'g', -1, 64) + "%", true
+	case UnitAuto:
+		return "auto", true
+	}
+	return nil, false
+}
+
+// marshalNumber encodes undefined numbers as null.
+func marshalNumber(number float64) interface{} {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil
+	}
+	return number
+}
+
+func marshalLayout(node *Node) map[string]interface{} {
+	box := GetLayoutBox(node)
+	edges := func(edges LayoutEdges) map[string]interface{} {
+		return map[string]interface{}{
+			"left":   marshalNumber(edges.Left),
+			"top":    marshalNumber(edges.Top),
+			"right":  marshalNumber(edges.Right),
+			"bottom": marshalNumber(edges.Bottom),
+		}
+	}
+	return map[string]interface{}{
+		"direction":     node.layout.direction.String(),
+		"left":          marshalNumber(box.Left),
+		"top":           marshalNumber(box.Top),
+		"right":         marshalNumber(node.layout.position[EdgeRight]),
+		"bottom":        marshalNumber(node.layout.position[EdgeBottom]),
+		"width":         marshalNumber(box.Width),
+		"height":        marshalNumber(box.Height),
+		"margin":        edges(box.Margin),
+		"border":        edges(box.Border),
+		"padding":       edges(box.Padding),
+		"contentWidth":  marshalNumber(node.layout.contentDimensions[DimensionWidth]),
+		"contentHeight": marshalNumber(node.layout.contentDimensions[DimensionHeight]),
+		"hadOverflow":   node.layout.hadOverflow,
+	}
+}
+
+// UnmarshalTree decodes a tree encoded by MarshalTree or
+// MarshalTreeWithLayout into new nodes using the default config. Errors name
+// the JSON path of the offending property, e.g. $.children[0].style.margin.
+func UnmarshalTree(data []byte) (*Node, error) {
+	return unmarshalNode(json.RawMessage(data), "$")
+}
+
+func unmarshalNode(data json.RawMessage, path string) (*Node, error) {
+	object, err := unmarshalObject(data, path)
+	if err != nil {
+		return nil, err
+	}
+	node := NewNode()
+	for _, key := range sortedKeys(object) {
+		keyPath := path + "." + key
+		switch key {
+		case "style":
+			err = unmarshalStyle(&node.style, object[key], keyPath)
+		case "layout":
+			err = unmarshalLayout(node, object[key], keyPath)
+		case "children":
+			err = unmarshalChildren(node, object[key], keyPath)
+		default:
+			err = unknownProperty(key, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func unmarshalChildren(node *Node, data json.RawMessage, path string) error {
+	var children []json.RawMessage
+	if err := json.Unmarshal(data, &children); err != nil {
+		return fmt.Errorf("Expected an array at %s", path)
+	}
+	for i, data := range children {
+		child, err := unmarshalNode(data, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return err
+		}
+		if err := InsertChild(node, child, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalStyle(style *Style, data json.RawMessage, path string) error {
+	object, err := unmarshalObject(data, path)
+	if err != nil {
+		return err
+	}
+properties:
+	for _, key := range sortedKeys(object) {
+		data, keyPath := object[key], path+"."+key
+		for _, enum := range styleEnums {
+			if enum.name == key {
+				i, err := unmarshalEnum(data, keyPath, enum.count, enum.names)
+				if err != nil {
+					return err
+				}
+				enum.set(style, i)
+				continue properties
+			}
+		}
+		for _, number := range styleNumbers {
+			if number.name == key {
+				if *number.field(style), err = unmarshalNumber(data, keyPath); err != nil {
+					return err
+				}
+				continue properties
+			}
+		}
+		for _, value := range styleValues {
+			if value.name == key {
+				if *value.field(style), err = unmarshalValue(data, keyPath); err != nil {
+					return err
+				}
+				continue properties
+			}
+		}
+		for _, edges := range styleEdges {
+			if edges.name == key {
+				if err := unmarshalEdges(edges.field(style), data, keyPath); err != nil {
+					return err
+				}
+				continue properties
+			}
+		}
+		if key == "gap" {
+			if err := unmarshalGap(style, data, keyPath); err != nil {
+				return err
+			}
+			continue
+		}
+		return unknownProperty(key, path)
+	}
+	return nil
+}
+
+func unmarshalEdges(edges *[11]Value, data json.RawMessage, path string) error {
+	object, err := unmarshalObject(data, path)
+	if err != nil {
+		return err
+	}
+keys:
+	for _, key := range sortedKeys(object) {
+		for edge := EdgeLeft; edge <= EdgeBlockEnd; edge++ {
+			if edge.String() == key {
+				if edges[edge], err = unmarshalValue(object[key], path+"."+key); err != nil {
+					return err
+				}
+				continue keys
+			}
+		}
+		return unknownProperty(key, path)
+	}
+	return nil
+}
+
+func unmarshalGap(style *Style, data json.RawMessage, path string) error {
+	object, err := unmarshalObject(data, path)
+	if err != nil {
+		return err
+	}
+keys:
+	for _, key := range sortedKeys(object) {
+		for gutter := GutterColumn; gutter <= GutterAll; gutter++ {
+			if gutter.String() == key {
+				if style.gap[gutter], err = unmarshalValue(object[key], path+"."+key); err != nil {
+					return err
+				}
+				continue keys
+			}
+		}
+		return unknownProperty(key, path)
+	}
+	return nil
+}
+
+func unmarshalLayout(node *Node, data json.RawMessage, path string) error {
+	object, err := unmarshalObject(data, path)
+	if err != nil {
+		return err
+	}
+	layout := &node.layout
+	// The direction decides how the horizontal edges are stored, so it is
+	// read before them.
+	if data, ok := object["direction"]; ok {
+		i, err := unmarshalEnum(data, path+".direction", int(DirectionRTL)+1,
+			func(i int) string { return Direction(i).String() })
+		if err != nil {
+			return err
+		}
+		layout.direction = Direction(i)
+	}
+	for _, key := range sortedKeys(object) {
+		data, keyPath := object[key], path+"."+key
+		switch key {
+		case "direction":
+		case "left":
+			layout.position[EdgeLeft], err = unmarshalNumber(data, keyPath)
+		case "top":
+			layout.position[EdgeTop], err = unmarshalNumber(data, keyPath)
+		case "right":
+			layout.position[EdgeRight], err = unmarshalNumber(data, keyPath)
+		case "bottom":
+			layout.position[EdgeBottom], err = unmarshalNumber(data, keyPath)
+		case "width":
+			layout.dimensions[DimensionWidth], err = unmarshalNumber(data, keyPath)
+			layout.measuredDimensions[DimensionWidth] = layout.dimensions[DimensionWidth]
+		case "height":
+			layout.dimensions[DimensionHeight], err = unmarshalNumber(data, keyPath)
+			layout.measuredDimensions[DimensionHeight] = layout.dimensions[DimensionHeight]
+		case "margin":
+			err = unmarshalLayoutEdges(node, &layout.margin, data, keyPath)
+		case "border":
+			err = unmarshalLayoutEdges(node, &layout.border, data, keyPath)
+		case "padding":
+			err = unmarshalLayoutEdges(node, &layout.padding, data, keyPath)
+		case "contentWidth":
+			layout.contentDimensions[DimensionWidth], err = unmarshalNumber(data, keyPath)
+		case "contentHeight":
+			layout.contentDimensions[DimensionHeight], err = unmarshalNumber(data, keyPath)
+		case "hadOverflow":
+			if json.Unmarshal(data, &layout.hadOverflow) != nil {
+				err = fmt.Errorf("Expected a boolean at %s", keyPath)
+			}
+		default:
+			err = unknownProperty(key, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalLayoutEdges decodes the physical edges encoded by marshalLayout
+// into values, the layout margin, border or padding of node.
+func unmarshalLayoutEdges(node *Node, values *[6]float64, data json.RawMessage, path string) error {
+	object, err := unmarshalObject(data, path)
+	if err != nil {
+		return err
+	}
+	isRTL := node.layout.direction == DirectionRTL
+	for _, key := range sortedKeys(object) {
+		var edge Edge
+		switch key {
+		case "left":
+			edge = EdgeStart
+			if isRTL {
+				edge = EdgeEnd
+			}
+		case "right":
+			edge = EdgeEnd
+			if isRTL {
+				edge = EdgeStart
+			}
+		case "top":
+			edge = EdgeTop
+		case "bottom":
+			edge = EdgeBottom
+		default:
+			return unknownProperty(key, path)
+		}
+		if values[edge], err = unmarshalNumber(object[key], path+"."+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalObject(data json.RawMessage, path string) (map[string]json.RawMessage, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return nil, fmt.Errorf("Expected an object at %s", path)
+	}
+	return object, nil
+}
+
+func unmarshalEnum(data json.RawMessage, path string, count int, names func(i int) string) (int, error) {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return 0, fmt.Errorf("Expected a string at %s", path)
+	}
+	for i := 0; i < count; i++ {
+		if names(i) == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown value %q at %s", name, path)
+}
+
+// unmarshalNumber decodes a number, or null for an undefined one.
+func unmarshalNumber(data json.RawMessage, path string) (float64, error) {
+	var number *float64
+	if err := json.Unmarshal(data, &number); err != nil {
+		return 0, fmt.Errorf("Expected a number at %s", path)
+	}
+	if number == nil {
+		return math.NaN(), nil
+	}
+	return *number, nil
+}
+
+// unmarshalValue decodes the encodings of marshalValue, or null for an
+// undefined value.
+func unmarshalValue(data json.RawMessage, path string) (Value, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return Value{}, fmt.Errorf("Invalid value at %s", path)
+	}
+	switch v := v.(type) {
+	case nil:
+		return Value{value: math.NaN(), unit: UnitUndefined}, nil
+	case float64:
+		return Value{value: v, unit: UnitPixel}, nil
+	case string:
+		if v == "auto" {
+			return Value{value: math.NaN(), unit: UnitAuto}, nil
+		}
+		if strings.HasSuffix(v, "%") {
+			if percent, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64); err == nil {
+				return Value{value: percent, unit: UnitPercent}, nil
+			}
+		}
+	}
+	return Value{}, fmt.Errorf("Invalid value %s at %s", data, path)
+}
+
+func unknownProperty(key, path string) error {
+	return fmt.Errorf("Unknown property %q at %s", key, path)
+}
+
+// sortedKeys returns the keys of object in order, so that the first error in
+// a document is always the one reported.
+func sortedKeys(object map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,161 @@
+package yoga
+
+import (
+	"math"
+	"testing"
+)
+
+// newStyledTree returns a tree that sets every kind of style property.
+func newStyledTree() *Node {
+	root := NewNode()
+	SetDirection(root, DirectionRTL)
+	SetWritingMode(root, WritingModeVerticalLR)
+	SetFlexDirection(root, FlexDirectionRowReverse)
+	SetJustifyContent(root, JustifySpaceEvenly)
+	SetAlignContent(root, AlignSpaceAround)
+	SetFlexWrap(root, WrapWrapReverse)
+	SetOverflow(root, OverflowScroll)
+	SetWidth(root, 300)
+	SetHeightPercent(root, 80)
+	SetMaxWidth(root, 500)
+	SetMinHeight(root, 10)
+	SetPadding(root, EdgeAll, 4)
+	SetPadding(root, EdgeBlockStart, 6)
+	SetBorder(root, EdgeHorizontal, 1)
+	SetGap(root, GutterAll, 3)
+	SetGapPercent(root, GutterRow, 5)
+
+	child := NewNode()
+	SetAlignSelf(child, AlignBaseLine)
+	SetPositionType(child, PositionTypeAbsolute)
+	SetDisplay(child, DisplayContents)
+	SetFlex(child, 2)
+	SetFlexGrow(child, 1.5)
+	SetFlexShrink(child, 0.5)
+	SetFlexBasisAuto(child)
+	SetAspectRatio(child, 1.25)
+	SetMarginAuto(child, EdgeStart)
+	SetMarginPercent(child, EdgeVertical, 10)
+	SetPosition(child, EdgeEnd, 7)
+	SetPositionPercent(child, EdgeTop, 20)
+	InsertChild(root, child, 0)
+	grandchild := NewNode()
+	SetAlignItems(grandchild, AlignCenter)
+	SetWidthAuto(grandchild)
+	InsertChild(child, grandchild, 0)
+	return root
+}
+
+func TestMarshalTreeRoundTrip(t *testing.T) {
+	data, err := MarshalTree(newStyledTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := UnmarshalTree(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := MarshalTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip changed the tree from\n%s\nto\n%s", data, again)
+	}
+
+	child := GetChild(root, 0)
+	if GetChildCount(root) != 1 || GetChildCount(child) != 1 || GetParent(child) != root {
+		t.Fatal("round trip changed the hierarchy")
+	}
+	if margin, _ := GetMargin(child, EdgeStart); margin.unit != UnitAuto {
+		t.Errorf("margin start = %v, want auto", margin)
+	}
+	if margin, _ := GetMargin(child, EdgeTop); margin.unit != UnitPercent || margin.value != 10 {
+		t.Errorf("margin top = %v, want 10%%", margin)
+	}
+	if GetWritingMode(root) != WritingModeVerticalLR || GetAlignSelf(child) != AlignBaseLine {
+		t.Error("round trip changed the enums")
+	}
+	if GetFlexGrow(child) != 1.5 || GetAspectRatio(child) != 1.25 {
+		t.Error("round trip changed the numbers")
+	}
+}
+
+func TestMarshalTreeDefaults(t *testing.T) {
+	data, err := MarshalTree(NewNode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"style\": {}\n}"; string(data) != want {
+		t.Errorf("MarshalTree(NewNode()) = %s, want %s", data, want)
+	}
+}
+
+func TestMarshalTreeWithLayoutRoundTrip(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetWidth(root, 100)
+	SetHeight(root, 50)
+	SetOverflow(root, OverflowHidden)
+	child := newSizedChild(root, 30, 60)
+	SetMargin(child, EdgeStart, 5)
+	SetBorder(child, EdgeLeft, 2)
+	SetPadding(child, EdgeEnd, 3)
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionRTL); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MarshalTreeWithLayout(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := UnmarshalTree(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := MarshalTreeWithLayout(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip changed the tree from\n%s\nto\n%s", data, again)
+	}
+	decodedChild := GetChild(decoded, 0)
+	if GetLayoutBox(decodedChild) != GetLayoutBox(child) {
+		t.Errorf("decoded layout box = %+v, want %+v", GetLayoutBox(decodedChild), GetLayoutBox(child))
+	}
+	if !GetLayoutHadOverflow(decoded) || GetLayoutDirection(decodedChild) != DirectionRTL {
+		t.Error("round trip lost the overflow flag or the direction")
+	}
+}
+
+func TestUnmarshalTreeErrors(t *testing.T) {
+	for _, test := range []struct {
+		data string
+		err  string
+	}{
+		{`[]`, `Expected an object at $`},
+		{`{"styles": {}}`, `Unknown property "styles" at $`},
+		{`{"style": 1}`, `Expected an object at $.style`},
+		{`{"style": {"colour": "red"}}`, `Unknown property "colour" at $.style`},
+		{`{"style": {"flexDirection": "diagonal"}}`, `Unknown value "diagonal" at $.style.flexDirection`},
+		{`{"style": {"display": 1}}`, `Expected a string at $.style.display`},
+		{`{"style": {"flexGrow": "1"}}`, `Expected a number at $.style.flexGrow`},
+		{`{"style": {"width": "wide"}}`, `Invalid value "wide" at $.style.width`},
+		{`{"style": {"width": "10px"}}`, `Invalid value "10px" at $.style.width`},
+		{`{"style": {"margin": {"middle": 1}}}`, `Unknown property "middle" at $.style.margin`},
+		{`{"style": {"padding": {"top": true}}}`, `Invalid value true at $.style.padding.top`},
+		{`{"style": {"gap": {"diagonal": 1}}}`, `Unknown property "diagonal" at $.style.gap`},
+		{`{"children": {}}`, `Expected an array at $.children`},
+		{`{"children": [{}, {"style": {"height": "x%"}}]}`, `Invalid value "x%" at $.children[1].style.height`},
+		{`{"layout": {"depth": 1}}`, `Unknown property "depth" at $.layout`},
+		{`{"layout": {"direction": "up"}}`, `Unknown value "up" at $.layout.direction`},
+		{`{"layout": {"hadOverflow": 1}}`, `Expected a boolean at $.layout.hadOverflow`},
+		{`{"layout": {"margin": {"start": 1}}}`, `Unknown property "start" at $.layout.margin`},
+	} {
+		_, err := UnmarshalTree([]byte(test.data))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("UnmarshalTree(%s) = %v, want %s", test.data, err, test.err)
+		}
+	}
+}
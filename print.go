@@ -0,0 +1,319 @@
+package yoga
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// nodePrinter writes an indented JSON document. It keeps the first error
+// returned by the underlying writer and drops all output after it.
+type nodePrinter struct {
+	w   io.Writer
+	err error
+	// entries holds the number of entries written to each open object or
+	// array, innermost last.
+	entries []int
+}
+
+func (p *nodePrinter) write(s string) {
+	if p.err == nil {
+		_, p.err = io.WriteString(p.w, s)
+	}
+}
+
+func (p *nodePrinter) indent() {
+	p.write("\n" + strings.Repeat("  ", len(p.entries)))
+}
+
+// entry starts a new entry of the innermost object, or of the innermost array
+// when key is empty.
+func (p *nodePrinter) entry(key string) {
+	if len(p.entries) == 0 {
+		return
+	}
+	if p.entries[len(p.entries)-1] > 0 {
+		p.write(",")
+	}
+	p.entries[len(p.entries)-1]++
+	p.indent()
+	if key != "" {
+		p.write(strconv.Quote(key) + ": ")
+	}
+}
+
+func (p *nodePrinter) begin(key, open string) {
+	p.entry(key)
+	p.write(open)
+	p.entries = append(p.entries, 0)
+}
+
+func (p *nodePrinter) end(close string) {
+	count := p.entries[len(p.entries)-1]
+	p.entries = p.entries[:len(p.entries)-1]
+	if count > 0 {
+		p.indent()
+	}
+	p.write(close)
+}
+
+func (p *nodePrinter) field(key, value string) {
+	p.entry(key)
+	p.write(value)
+}
+
+func formatNumber(number float64) string {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return "null"
+	}
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+// formatValue formats points as numbers and percentages and auto as strings.
+func formatValue(value *Value) string {
+	switch value.unit {
+	case UnitPixel:
+		return formatNumber(value.value)
+	case UnitPercent:
+		return strconv.Quote(formatNumber(value.value) + "%")
+	case UnitAuto:
+		return strconv.Quote("auto")
+	}
+	return "null"
+}
+
+func (p *nodePrinter) enum(key string, value fmt.Stringer) {
+	p.field(key, strconv.Quote(value.String()))
+}
+
+func (p *nodePrinter) numberIfNotUndefined(key string, number float64) {
+	if !math.IsNaN(number) {
+		p.field(key, formatNumber(number))
+	}
+}
+
+func (p *nodePrinter) valueIfNotUndefined(key string, value *Value) {
+	if value.unit != UnitUndefined {
+		p.field(key, formatValue(value))
+	}
+}
+
+func (p *nodePrinter) valueIfNotZero(key string, value *Value) {
+	if value.unit != UnitUndefined && !FloatsEqual(value.value, 0) {
+		p.field(key, formatValue(value))
+	}
+}
+
+func FourValuesEqual(four [4]Value) bool {
+	return ValueEqual(four[0], four[1]) && ValueEqual(four[0], four[2]) && ValueEqual(four[0], four[3])
+}
+
+var printedEdges = []struct {
+	edge Edge
+	name string
+}{
+	{EdgeLeft, "Left"},
+	{EdgeRight, "Right"},
+	{EdgeTop, "Top"},
+	{EdgeBottom, "Bottom"},
+	{EdgeStart, "Start"},
+	{EdgeEnd, "End"},
+	{EdgeBlockStart, "BlockStart"},
+	{EdgeBlockEnd, "BlockEnd"},
+}
+
+// printedEdgeValue returns the value printed for edge. Physical edges fall
+// back to the shorthands that set them, but logical edges are only printed
+// when they are set themselves.
+func printedEdgeValue(edges [11]Value, edge Edge, defaultValue *Value) (*Value, error) {
+	if edge == EdgeStart || edge == EdgeEnd || edge == EdgeBlockStart || edge == EdgeBlockEnd {
+		return &edges[edge], nil
+	}
+	return ComputedEdgeValue(edges, edge, defaultValue)
+}
+
+// edgesIfNotZero prints the margin, border or padding in edges as a single
+// name+suffix property when it is the same on all edges, and as one property
+// per edge otherwise.
+func (p *nodePrinter) edgesIfNotZero(name, suffix string, edges [11]Value) error {
+	var fourVal [4]Value
+	for edge := EdgeLeft; edge <= EdgeBottom; edge++ {
+		val, err := ComputedEdgeValue(edges, edge, &Value{value: 0, unit: UnitPixel})
+		if err != nil {
+			return err
+		}
+		fourVal[edge] = *val
+	}
+	logicalUndefined := true
+	for _, edge := range []Edge{EdgeStart, EdgeEnd, EdgeBlockStart, EdgeBlockEnd} {
+		if edges[edge].unit != UnitUndefined {
+			logicalUndefined = false
+		}
+	}
+	if FourValuesEqual(fourVal) && logicalUndefined {
+		p.valueIfNotZero(name+suffix, &fourVal[EdgeLeft])
+		return nil
+	}
+	for _, e := range printedEdges {
+		val, err := printedEdgeValue(edges, e.edge, &Value{value: 0, unit: UnitPixel})
+		if err != nil {
+			return err
+		}
+		p.valueIfNotZero(name+e.name+suffix, val)
+	}
+	return nil
+}
+
+func (p *nodePrinter) printStyle(node *Node) error {
+	p.begin("style", "{")
+	p.enum("direction", node.style.direction)
+	p.enum("writingMode", node.style.writingMode)
+	p.enum("flexDirection", node.style.flexDirection)
+	p.enum("justifyContent", node.style.justifyContent)
+	p.enum("alignItems", node.style.alignItems)
+	p.enum("alignContent", node.style.alignContent)
+	p.enum("alignSelf", node.style.alignSelf)
+	p.enum("flexWrap", node.style.flexWrap)
+	p.numberIfNotUndefined("flexGrow", GetFlexGrow(node))
+	p.numberIfNotUndefined("flexShrink", GetFlexShrink(node))
+	p.valueIfNotUndefined("flexBasis", GetFlexBasisPtr(node))
+	p.enum("overflow", node.style.overflow)
+	p.enum("display", node.style.display)
+	if err := p.edgesIfNotZero("margin", "", node.style.margin); err != nil {
+		return err
+	}
+	if err := p.edgesIfNotZero("border", "Width", node.style.border); err != nil {
+		return err
+	}
+	if err := p.edgesIfNotZero("padding", "", node.style.padding); err != nil {
+		return err
+	}
+	p.valueIfNotUndefined("gap", &node.style.gap[GutterAll])
+	p.valueIfNotUndefined("columnGap", &node.style.gap[GutterColumn])
+	p.valueIfNotUndefined("rowGap", &node.style.gap[GutterRow])
+	p.valueIfNotUndefined("width", &node.style.dimensions[DimensionWidth])
+	p.valueIfNotUndefined("height", &node.style.dimensions[DimensionHeight])
+	p.valueIfNotUndefined("maxWidth", &node.style.maxDimensions[DimensionWidth])
+	p.valueIfNotUndefined("maxHeight", &node.style.maxDimensions[DimensionHeight])
+	p.valueIfNotUndefined("minWidth", &node.style.minDimensions[DimensionWidth])
+	p.valueIfNotUndefined("minHeight", &node.style.minDimensions[DimensionHeight])
+	p.numberIfNotUndefined("aspectRatio", node.style.aspectRatio)
+	p.enum("position", node.style.positionType)
+	for _, e := range printedEdges {
+		val, err := printedEdgeValue(node.style.position, e.edge, &Value{value: math.NaN(), unit: UnitUndefined})
+		if err != nil {
+			return err
+		}
+		p.valueIfNotUndefined(strings.ToLower(e.name[:1])+e.name[1:], val)
+	}
+	p.end("}")
+	return nil
+}
+
+func (p *nodePrinter) printNode(node *Node, options PrintOptions) error {
+	if node.print != nil {
+		node.print(node)
+	}
+	p.begin("", "{")
+	if options&PrintOptionsLayout != 0 {
+		p.begin("layout", "{")
+		p.field("width", formatNumber(node.layout.dimensions[DimensionWidth]))
+		p.field("height", formatNumber(node.layout.dimensions[DimensionHeight]))
+		p.field("top", formatNumber(node.layout.position[EdgeTop]))
+		p.field("left", formatNumber(node.layout.position[EdgeLeft]))
+		p.end("}")
+	}
+	if options&PrintOptionsStyle != 0 {
+		if err := p.printStyle(node); err != nil {
+			return err
+		}
+	}
+	if options&PrintOptionsChildren != 0 && len(node.children) > 0 {
+		p.begin("children", "[")
+		for _, child := range node.children {
+			if err := p.printNode(child, options); err != nil {
+				return err
+			}
+		}
+		p.end("]")
+	}
+	p.end("}")
+	return nil
+}
+
+// NodePrintTo writes node to w as an indented JSON document. options select
+// whether the layout and the style of node are included, and whether its
+// children are printed in a "children" array. Undefined numbers are written
+// as null. The print function of each node, if any, is called before the node
+// is written.
+func NodePrintTo(w io.Writer, node *Node, options PrintOptions) error {
+	p := &nodePrinter{w: w}
+	if err := p.printNode(node, options); err != nil {
+		return err
+	}
+	p.write("\n")
+	return p.err
+}
+
+// NodePrint logs the document written by NodePrintTo.
+func NodePrint(node *Node, options PrintOptions) error {
+	return NodePrintInternal(node, options, 0)
+}
+
+// NodePrintInternal logs the document written by NodePrintTo, indented by
+// level.
+//
+// Deprecated: Use NodePrintTo.
+func NodePrintInternal(node *Node, options PrintOptions, level int) error {
+	var buf bytes.Buffer
+	if err := NodePrintTo(&buf, node, options); err != nil {
+		return err
+	}
+	prefix := strings.Repeat("  ", level)
+	log.Print(prefix + strings.Replace(strings.TrimSuffix(buf.String(), "\n"), "\n", "\n"+prefix, -1))
+	return nil
+}
+
+// Indent logs n levels of indentation.
+//
+// Deprecated: NodePrintTo indents its output itself.
+func Indent(n int) {
+	for i := 0; i < n; i++ {
+		log.Println("  ")
+	}
+}
+
+// PrintNumberIfNotZero logs number under str unless it is zero.
+//
+// Deprecated: Use NodePrintTo.
+func PrintNumberIfNotZero(str string, number *Value) {
+	if number.unit == UnitAuto {
+		log.Printf("%s: auto, ", str)
+	} else if !FloatsEqual(number.value, 0) {
+		log.Printf("%s: %g%s, ", str, number.value, number.unit)
+	}
+}
+
+// PrintNumberIfNotUndefinedf logs number under str unless it is undefined.
+//
+// Deprecated: Use NodePrintTo.
+func PrintNumberIfNotUndefinedf(str string, number float64) {
+	if !math.IsNaN(number) {
+		log.Printf("%s: %g, ", str, number)
+	}
+}
+
+// PrintNumberIfNotUndefined logs number under str unless it is undefined.
+//
+// Deprecated: Use NodePrintTo.
+func PrintNumberIfNotUndefined(str string, number *Value) {
+	if number.unit == UnitAuto {
+		log.Printf("%s: auto, ", str)
+	} else if number.unit != UnitUndefined {
+		log.Printf("%s: %g%s, ", str, number.value, number.unit)
+	}
+}
@@ -0,0 +1,198 @@
+package yoga
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNodePrintGap(t *testing.T) {
+	node := NewNode()
+	SetGap(node, GutterAll, 4)
+	SetGapPercent(node, GutterRow, 10)
+	out := printStyle(t, node)
+	for _, want := range []string{`"gap": 4`, `"rowGap": "10%"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "columnGap") {
+		t.Errorf("output contains the undefined column gap:\n%s", out)
+	}
+}
+
+func printStyle(t *testing.T, node *Node) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NodePrintTo(&buf, node, PrintOptionsStyle); err != nil {
+		t.Fatalf("NodePrintTo: %v", err)
+	}
+	return buf.String()
+}
+
+func TestNodePrintLogicalEdgesOnlyWhenSet(t *testing.T) {
+	node := NewNode()
+	SetMargin(node, EdgeHorizontal, 3)
+	SetMargin(node, EdgeTop, 1)
+	SetPadding(node, EdgeAll, 2)
+	SetPadding(node, EdgeStart, 4)
+	SetPosition(node, EdgeAll, 5)
+
+	out := printStyle(t, node)
+	for _, want := range []string{`"marginLeft": 3`, `"marginRight": 3`, `"marginTop": 1`,
+		`"paddingLeft": 2`, `"paddingBottom": 2`, `"paddingStart": 4`, `"left": 5`, `"bottom": 5`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %s:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"marginStart", "marginEnd", "marginBlockStart", "paddingEnd",
+		"paddingBlockStart", `"start"`, `"end"`, "blockStart", "blockEnd"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output contains %s:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestNodePrintTo(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetHeight(root, 50)
+	newSizedChild(root, 10, 10)
+	calculateLayout(t, root)
+
+	var buf bytes.Buffer
+	if err := NodePrintTo(&buf, root, PrintOptionsLayout|PrintOptionsChildren); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "layout": {
+    "width": 100,
+    "height": 50,
+    "top": 0,
+    "left": 0
+  },
+  "children": [
+    {
+      "layout": {
+        "width": 10,
+        "height": 10,
+        "top": 0,
+        "left": 0
+      }
+    }
+  ]
+}
+`
+	if buf.String() != want {
+		t.Errorf("NodePrintTo wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := NodePrintTo(&buf, root, PrintOptionsLayout); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "children") {
+		t.Errorf("output without PrintOptionsChildren contains children:\n%s", buf.String())
+	}
+}
+
+func TestNodePrintToWritesValidJSON(t *testing.T) {
+	root := NewNode()
+	SetMargin(root, EdgeAll, 5)
+	SetPadding(root, EdgeVertical, 2)
+	SetPadding(root, EdgeHorizontal, 3)
+	SetBorder(root, EdgeTop, 1)
+	SetFlexBasisPercent(root, 50)
+	SetWidthAuto(root)
+	SetPosition(root, EdgeLeft, math.NaN())
+	SetAspectRatio(root, 1.5)
+	child := NewNode()
+	InsertChild(root, child, 0)
+	printed := 0
+	SetPrintFunc(child, func(node *Node) {
+		printed++
+	})
+
+	// The layout of nodes that were never laid out is undefined.
+	var buf bytes.Buffer
+	if err := NodePrintTo(&buf, root, PrintOptionsLayout|PrintOptionsStyle|PrintOptionsChildren); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if printed != 1 {
+		t.Errorf("print function called %d times, want 1", printed)
+	}
+	style := doc["style"].(map[string]interface{})
+	for key, want := range map[string]interface{}{
+		"margin":         5.0,
+		"paddingTop":     2.0,
+		"paddingLeft":    3.0,
+		"borderTopWidth": 1.0,
+		"flexBasis":      "50%",
+		"width":          "auto",
+		"aspectRatio":    1.5,
+	} {
+		if style[key] != want {
+			t.Errorf("style[%q] = %v, want %v", key, style[key], want)
+		}
+	}
+	for _, key := range []string{"padding", "borderBottomWidth", "left", "height"} {
+		if _, ok := style[key]; ok {
+			t.Errorf("style contains %q", key)
+		}
+	}
+	if layout := doc["layout"].(map[string]interface{}); layout["width"] != nil {
+		t.Errorf("layout width = %v, want null", layout["width"])
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestNodePrintToReturnsWriteErrors(t *testing.T) {
+	if err := NodePrintTo(failingWriter{}, NewNode(), PrintOptionsStyle); err == nil || err.Error() != "write failed" {
+		t.Errorf("NodePrintTo = %v, want the error of the writer", err)
+	}
+}
+
+func TestNodePrintLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	node := NewNode()
+	SetWidth(node, 10)
+	if err := NodePrintInternal(node, PrintOptionsStyle, 1); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if lines[0] != "  {" || lines[len(lines)-1] != "  }" || !strings.Contains(buf.String(), "\n      \"width\": 10") {
+		t.Errorf("NodePrintInternal logged\n%s", buf.String())
+	}
+
+	buf.Reset()
+	PrintNumberIfNotZero("margin", &Value{0, UnitPixel})
+	PrintNumberIfNotZero("padding", &Value{5, UnitPercent})
+	PrintNumberIfNotUndefinedf("flexGrow", math.NaN())
+	PrintNumberIfNotUndefinedf("flexShrink", 1)
+	PrintNumberIfNotUndefined("width", &Value{math.NaN(), UnitUndefined})
+	PrintNumberIfNotUndefined("height", &Value{math.NaN(), UnitAuto})
+	if want := "padding: 5%, \nflexShrink: 1, \nheight: auto, \n"; buf.String() != want {
+		t.Errorf("print helpers logged %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,166 @@
+package yoga
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+)
+
+// SVGOptions configures RenderSVG.
+type SVGOptions struct {
+	// Name returns the label drawn on node, or "" for none. Nodes are not
+	// labeled when it is nil.
+	Name func(node *Node) string
+	// Scale is the number of SVG pixels per point. Zero means 1.
+	Scale float64
+}
+
+// svgStyleSheet shades the areas of each box in the colors browsers use to
+// highlight them.
+const svgStyleSheet = `<style>
+  .margin { fill: #f9cc9d; fill-opacity: 0.6; }
+  .border { fill: #fddd9b; }
+  .padding { fill: #c3d08b; fill-opacity: 0.6; }
+  .box { fill: none; stroke: #333; stroke-width: 1; vector-effect: non-scaling-stroke; }
+  .overflow .box { stroke: red; stroke-width: 2; }
+  .overflow .padding { fill: red; fill-opacity: 0.2; }
+  .label { font: 10px monospace; fill: #333; }
+</style>
+`
+
+// svgBox is a rectangle in the coordinates of the root.
+type svgBox struct {
+	left, top, right, bottom float64
+}
+
+func (b svgBox) union(other svgBox) svgBox {
+	return svgBox{
+		left:   math.Min(b.left, other.left),
+		top:    math.Min(b.top, other.top),
+		right:  math.Max(b.right, other.right),
+		bottom: math.Max(b.bottom, other.bottom),
+	}
+}
+
+// inset returns b shrunk by edges.
+func (b svgBox) inset(edges LayoutEdges) svgBox {
+	return svgBox{b.left + edges.Left, b.top + edges.Top, b.right - edges.Right, b.bottom - edges.Bottom}
+}
+
+// contains reports whether other lies within b, allowing for rounding.
+func (b svgBox) contains(other svgBox) bool {
+	const epsilon = 0.0001
+	return other.left >= b.left-epsilon && other.top >= b.top-epsilon &&
+		other.right <= b.right+epsilon && other.bottom <= b.bottom+epsilon
+}
+
+// svgBoxes returns the margin, border, padding and content boxes of node,
+// whose parent has its border box at left and top.
+func svgBoxes(node *Node, left, top float64) (margin, border, padding, content svgBox) {
+	box := GetLayoutBox(node)
+	border = svgBox{left + box.Left, top + box.Top, left + box.Left + box.Width, top + box.Top + box.Height}
+	margin = border.inset(LayoutEdges{-box.Margin.Left, -box.Margin.Top, -box.Margin.Right, -box.Margin.Bottom})
+	padding = border.inset(box.Border)
+	content = padding.inset(box.Padding)
+	return
+}
+
+// svgBounds returns the box that holds the margin and border boxes of node
+// and all of its drawn descendants. Negative margins can pull the border box
+// past the margin box.
+func svgBounds(node *Node, left, top float64) svgBox {
+	margin, border, _, _ := svgBoxes(node, left, top)
+	bounds := margin.union(border)
+	for _, child := range node.children {
+		if child.style.display != DisplayNone {
+			bounds = bounds.union(svgBounds(child, border.left, border.top))
+		}
+	}
+	return bounds
+}
+
+// RenderSVG draws the computed layout of root and its descendants to w as an
+// SVG image. The border box of each node is outlined and its margin, border
+// and padding areas are shaded. Children that extend past the padding box of
+// their parent are drawn in red. root must have been laid out.
+func RenderSVG(root *Node, w io.Writer, opts SVGOptions) error {
+	if math.IsNaN(root.layout.dimensions[DimensionWidth]) || math.IsNaN(root.layout.dimensions[DimensionHeight]) {
+		return errors.New("Cannot render a node that has not been laid out")
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	bounds := svgBounds(root, 0, 0)
+	width, height := bounds.right-bounds.left, bounds.bottom-bounds.top
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"%s %s %s %s\">\n",
+		formatNumber(width*scale), formatNumber(height*scale),
+		formatNumber(bounds.left), formatNumber(bounds.top), formatNumber(width), formatNumber(height))
+	fmt.Fprint(bw, svgStyleSheet)
+	renderSVGNode(bw, root, 0, 0, nil, opts)
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// renderSVGNode draws node and its descendants. container is the padding box
+// of the node that lays node out, or nil for the root.
+func renderSVGNode(w io.Writer, node *Node, left, top float64, container *svgBox, opts SVGOptions) {
+	margin, border, padding, content := svgBoxes(node, left, top)
+
+	// Nodes with DisplayContents have no box of their own, but their
+	// children are drawn.
+	if node.style.display == DisplayFlex {
+		name := ""
+		if opts.Name != nil {
+			name = opts.Name(node)
+		}
+		class := "node"
+		if container != nil && !container.contains(border) {
+			class += " overflow"
+		}
+		fmt.Fprintf(w, "<g class=\"%s\">\n", class)
+		if name != "" {
+			fmt.Fprintf(w, "  <title>%s</title>\n", html.EscapeString(name))
+		}
+		writeSVGRing(w, "margin", margin, border)
+		writeSVGRing(w, "border", border, padding)
+		writeSVGRing(w, "padding", padding, content)
+		fmt.Fprintf(w, "  <rect class=\"box\" x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\"/>\n",
+			formatNumber(border.left), formatNumber(border.top),
+			formatNumber(border.right-border.left), formatNumber(border.bottom-border.top))
+		if name != "" {
+			fmt.Fprintf(w, "  <text class=\"label\" x=\"%s\" y=\"%s\">%s</text>\n",
+				formatNumber(border.left+2), formatNumber(border.top+11), html.EscapeString(name))
+		}
+		fmt.Fprintf(w, "</g>\n")
+	}
+
+	childContainer := &padding
+	if node.style.display == DisplayContents {
+		childContainer = container
+	}
+	for _, child := range node.children {
+		if child.style.display != DisplayNone {
+			renderSVGNode(w, child, border.left, border.top, childContainer, opts)
+		}
+	}
+}
+
+// writeSVGRing fills the area between outer and inner, if any, with the
+// style of class.
+func writeSVGRing(w io.Writer, class string, outer, inner svgBox) {
+	if outer == inner {
+		return
+	}
+	fmt.Fprintf(w, "  <path class=\"%s\" fill-rule=\"evenodd\" d=\"%s %s\"/>\n", class, svgRect(outer), svgRect(inner))
+}
+
+func svgRect(b svgBox) string {
+	return fmt.Sprintf("M%s %sH%sV%sH%sZ", formatNumber(b.left), formatNumber(b.top),
+		formatNumber(b.right), formatNumber(b.bottom), formatNumber(b.left))
+}
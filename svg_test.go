@@ -0,0 +1,106 @@
+package yoga
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func renderSVG(t *testing.T, root *Node, opts SVGOptions) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := RenderSVG(root, &buf, opts); err != nil {
+		t.Fatalf("RenderSVG: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRenderSVG(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetHeight(root, 50)
+	SetPadding(root, EdgeAll, 5)
+	child := newSizedChild(root, 20, 10)
+	SetMargin(child, EdgeLeft, 4)
+	SetBorder(child, EdgeAll, 1)
+	newSizedChild(root, 120, 10)
+	hidden := newSizedChild(root, 10, 10)
+	SetDisplay(hidden, DisplayNone)
+	SetMargin(hidden, EdgeAll, 200)
+	calculateLayout(t, root)
+
+	name := func(node *Node) string {
+		if node == child {
+			return "a<b>"
+		}
+		return ""
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" width="250" height="100" viewBox="0 0 125 50">
+` + svgStyleSheet + `<g class="node">
+  <path class="padding" fill-rule="evenodd" d="M0 0H100V50H0Z M5 5H95V45H5Z"/>
+  <rect class="box" x="0" y="0" width="100" height="50"/>
+</g>
+<g class="node">
+  <title>a&lt;b&gt;</title>
+  <path class="margin" fill-rule="evenodd" d="M5 5H29V15H5Z M9 5H29V15H9Z"/>
+  <path class="border" fill-rule="evenodd" d="M9 5H29V15H9Z M10 6H28V14H10Z"/>
+  <rect class="box" x="9" y="5" width="20" height="10"/>
+  <text class="label" x="11" y="16">a&lt;b&gt;</text>
+</g>
+<g class="node overflow">
+  <rect class="box" x="5" y="15" width="120" height="10"/>
+</g>
+</svg>
+`
+	if got := renderSVG(t, root, SVGOptions{Name: name, Scale: 2}); got != want {
+		t.Errorf("RenderSVG wrote\n%s\nwant\n%s", got, want)
+	}
+
+	// Without options nothing is labeled and the image is not scaled.
+	got := renderSVG(t, root, SVGOptions{})
+	if !strings.HasPrefix(got, `<svg xmlns="http://www.w3.org/2000/svg" width="125" height="50" viewBox="0 0 125 50">`) {
+		t.Errorf("RenderSVG without a scale wrote\n%s", got)
+	}
+	if strings.Contains(got, "<title>") || strings.Contains(got, "<text") {
+		t.Errorf("RenderSVG without a name function labeled nodes:\n%s", got)
+	}
+}
+
+func TestRenderSVGDisplayContents(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 50)
+	SetHeight(root, 50)
+	SetPadding(root, EdgeAll, 10)
+	contents := NewNode()
+	SetDisplay(contents, DisplayContents)
+	InsertChild(root, contents, 0)
+	child := newSizedChild(contents, 40, 10)
+	SetMargin(child, EdgeTop, -20)
+	calculateLayout(t, root)
+
+	// The child is checked against the padding box of the root, which lays
+	// it out, and the image extends to its border box.
+	got := renderSVG(t, root, SVGOptions{})
+	if !strings.Contains(got, `viewBox="0 -10 50 60"`) {
+		t.Errorf("RenderSVG did not fit the image to the border box:\n%s", got)
+	}
+	if n := strings.Count(got, "<g "); n != 2 {
+		t.Errorf("RenderSVG drew %d nodes, want 2:\n%s", n, got)
+	}
+	if !strings.Contains(got, "<g class=\"node overflow\">") || !strings.Contains(got, `<rect class="box" x="10" y="-10" width="40" height="10"/>`) {
+		t.Errorf("RenderSVG did not draw the overflowing child:\n%s", got)
+	}
+}
+
+func TestRenderSVGErrors(t *testing.T) {
+	err := RenderSVG(NewNode(), &bytes.Buffer{}, SVGOptions{})
+	if err == nil || err.Error() != "Cannot render a node that has not been laid out" {
+		t.Errorf("RenderSVG of a node that was not laid out = %v", err)
+	}
+
+	root := NewNode()
+	calculateLayout(t, root)
+	if err := RenderSVG(root, failingWriter{}, SVGOptions{}); err == nil || err.Error() != "write failed" {
+		t.Errorf("RenderSVG = %v, want the error of the writer", err)
+	}
+}
@@ -2,8 +2,8 @@ package yoga
 
 import (
 	"errors"
-	"log"
 	"math"
+	"sync/atomic"
 )
 
 type Size struct {
@@ -16,8 +16,10 @@ type Value struct {
 	unit  Unit
 }
 
+const maxCachedResultCount = 16
+
 type CachedMeasurement struct {
-	avaialbleWidth    float64
+	availableWidth    float64
 	availableHeight   float64
 	widthMeasureMode  MeasureMode
 	heightMeasureMode MeasureMode
@@ -30,15 +32,23 @@ type Layout struct {
 	dimensions                  [2]float64
 	margin                      [6]float64
 	padding                     [6]float64
+	border                      [6]float64
 	direction                   Direction
 	computedFlexBasisGeneration uint32
 	computedFlexBasis           float64
 	generationCount             uint32
 	lastParentDirection         Direction
 	nextCachedMeasurementsIndex uint32
-	cachedMeasurements          [16]CachedMeasurement
+	cachedMeasurements          [maxCachedResultCount]CachedMeasurement
 	measuredDimensions          [2]float64
 	cachedLayout                CachedMeasurement
+	contentDimensions           [2]float64
+	hadOverflow                 bool
+	// unroundedPosition and unroundedDimensions hold the layout computed
+	// for the node before RoundToPixelGrid rounded it, if rounded is set.
+	unroundedPosition   [4]float64
+	unroundedDimensions [2]float64
+	rounded             bool
 }
 
 type Style struct {
@@ -51,14 +61,17 @@ type Style struct {
 	positionType   PositionType
 	flexWrap       Wrap
 	overflow       Overflow
+	display        Display
+	writingMode    WritingMode
 	flex           float64
 	flexGrow       float64
 	flexShrink     float64
 	flexBasis      Value
-	margin         [9]Value
-	position       [9]Value
-	padding        [9]Value
-	border         [9]Value
+	margin         [11]Value
+	position       [11]Value
+	padding        [11]Value
+	border         [11]Value
+	gap            [3]Value
 	dimensions     [2]Value
 	minDimensions  [2]Value
 	maxDimensions  [2]Value
@@ -80,18 +93,146 @@ type Node struct {
 	baseLine     BaseLineFunc
 	print        PrintFunc
 	context      *interface{}
+	config       *Config
 	isDirty      bool
 	hasNewLayout bool
 }
 
-func ComputedEdgeValue(edges [9]Value, edge Edge, defaultValue *Value) (*Value, error) {
-	if !(edge <= EdgeEnd) {
+type Config struct {
+	pointScaleFactor float64
+}
+
+var defaultConfig = &Config{pointScaleFactor: 1.0}
+
+func NewConfig() *Config {
+	return &Config{pointScaleFactor: defaultConfig.pointScaleFactor}
+}
+
+// SetPointScaleFactor sets the number of physical pixels in a point that
+// layout results of nodes using config are rounded to. Zero disables
+// rounding.
+func SetPointScaleFactor(config *Config, pixelsInPoint float64) error {
+	if pixelsInPoint < 0.0 {
+		return errors.New("Scale factor should not be less than zero")
+	}
+	config.pointScaleFactor = pixelsInPoint
+	return nil
+}
+
+func GetPointScaleFactor(config *Config) float64 {
+	return config.pointScaleFactor
+}
+
+func nodeInit(node *Node) {
+	node.parent = nil
+	node.children = nil
+	node.hasNewLayout = true
+	node.isDirty = false
+
+	node.style.flex = math.NaN()
+	node.style.flexGrow = math.NaN()
+	node.style.flexShrink = math.NaN()
+	node.style.flexBasis = Value{value: math.NaN(), unit: UnitUndefined}
+
+	node.style.alignItems = AlignStretch
+	node.style.alignContent = AlignFlexStart
+
+	node.style.direction = DirectionInherit
+	node.style.flexDirection = FlexDirectionColumn
+
+	node.style.overflow = OverflowVisible
+
+	// Some of the fields default to undefined and not 0
+	for i := range node.style.dimensions {
+		node.style.dimensions[i] = Value{value: math.NaN(), unit: UnitUndefined}
+		node.style.minDimensions[i] = Value{value: math.NaN(), unit: UnitUndefined}
+		node.style.maxDimensions[i] = Value{value: math.NaN(), unit: UnitUndefined}
+	}
+	for edge := EdgeLeft; edge <= EdgeBlockEnd; edge++ {
+		node.style.position[edge] = Value{value: math.NaN(), unit: UnitUndefined}
+		node.style.margin[edge] = Value{value: math.NaN(), unit: UnitUndefined}
+		node.style.padding[edge] = Value{value: math.NaN(), unit: UnitUndefined}
+		node.style.border[edge] = Value{value: math.NaN(), unit: UnitUndefined}
+	}
+	for gutter := GutterColumn; gutter <= GutterAll; gutter++ {
+		node.style.gap[gutter] = Value{value: math.NaN(), unit: UnitUndefined}
+	}
+
+	node.style.aspectRatio = math.NaN()
+
+	// Layout
+	node.layout.dimensions[DimensionWidth] = math.NaN()
+	node.layout.dimensions[DimensionHeight] = math.NaN()
+	node.layout.lastParentDirection = Direction(-1)
+	node.layout.nextCachedMeasurementsIndex = 0
+	node.layout.computedFlexBasis = math.NaN()
+	node.layout.measuredDimensions[DimensionWidth] = math.NaN()
+	node.layout.measuredDimensions[DimensionHeight] = math.NaN()
+	node.layout.cachedLayout.widthMeasureMode = MeasureMode(-1)
+	node.layout.cachedLayout.heightMeasureMode = MeasureMode(-1)
+	node.layout.cachedLayout.computedWidth = -1
+	node.layout.cachedLayout.computedHeight = -1
+}
+
+func NewNodeWithConfig(config *Config) *Node {
+	node := &Node{config: config}
+	nodeInit(node)
+	return node
+}
+
+func NewNode() *Node {
+	return NewNodeWithConfig(defaultConfig)
+}
+
+func GetConfig(node *Node) *Config {
+	return node.config
+}
+
+func NodeFree(node *Node) {
+	if node.parent != nil {
+		RemoveChild(node.parent, node)
+	}
+	for _, child := range node.children {
+		child.parent = nil
+	}
+	*node = Node{config: node.config}
+	nodeInit(node)
+}
+
+func NodeFreeRecursive(root *Node) {
+	for GetChildCount(root) > 0 {
+		child := GetChild(root, 0)
+		RemoveChild(root, child)
+		NodeFreeRecursive(child)
+	}
+	NodeFree(root)
+}
+
+func NodeReset(node *Node) error {
+	if GetChildCount(node) != 0 {
+		return errors.New("Cannot reset a node which still has children attached")
+	}
+	if node.parent != nil {
+		return errors.New("Cannot reset a node still attached to a parent")
+	}
+	*node = Node{config: node.config}
+	nodeInit(node)
+	return nil
+}
+
+// ComputedEdgeValue returns the value in edges that applies to edge, falling
+// back to the shorthands that include it. The logical edges fall back as in
+// the horizontal-tb writing mode; ResolvedEdgeValue maps them to physical
+// edges for a node.
+func ComputedEdgeValue(edges [11]Value, edge Edge, defaultValue *Value) (*Value, error) {
+	if edgeIsShorthand(edge) {
 		return nil, errors.New("Cannot get computed value of multi-edge shorthands")
 	}
 	if edges[edge].unit != UnitUndefined {
 		return &edges[edge], nil
 	}
-	if (edge == EdgeTop || edge == EdgeBottom) && edges[EdgeVertical].unit != UnitUndefined {
+	if (edge == EdgeTop || edge == EdgeBottom || edge == EdgeBlockStart || edge == EdgeBlockEnd) &&
+		edges[EdgeVertical].unit != UnitUndefined {
 		return &edges[EdgeVertical], nil
 	}
 	if (edge == EdgeLeft || edge == EdgeRight || edge == EdgeStart || edge == EdgeEnd) &&
@@ -101,17 +242,74 @@ func ComputedEdgeValue(edges [9]Value, edge Edge, defaultValue *Value) (*Value,
 	if edges[EdgeAll].unit != UnitUndefined {
 		return &edges[EdgeAll], nil
 	}
-	if edge == EdgeStart || edge == EdgeEnd {
+	if edge == EdgeStart || edge == EdgeEnd || edge == EdgeBlockStart || edge == EdgeBlockEnd {
 		return &Value{value: math.NaN()}, nil
 	}
 	return defaultValue, nil
 }
 
+func edgeIsShorthand(edge Edge) bool {
+	return edge == EdgeHorizontal || edge == EdgeVertical || edge == EdgeAll
+}
+
+// physicalEdge maps the logical edges to the physical edge they stand for in
+// the writing mode and layout direction of node.
+func physicalEdge(node *Node, edge Edge) Edge {
+	inlineAxis := FlexDirectionResolveWritingMode(FlexDirectionRow, node.layout.direction, node.style.writingMode)
+	blockAxis := FlexDirectionResolveWritingMode(FlexDirectionColumn, node.layout.direction, node.style.writingMode)
+	switch edge {
+	case EdgeStart:
+		return leading[inlineAxis]
+	case EdgeEnd:
+		return trailing[inlineAxis]
+	case EdgeBlockStart:
+		return leading[blockAxis]
+	case EdgeBlockEnd:
+		return trailing[blockAxis]
+	}
+	return edge
+}
+
+// styleEdgeValue is like ComputedEdgeValue, but the logical edges fall back
+// to the shorthand of the physical axis they lie on in the writing mode of
+// node.
+func styleEdgeValue(node *Node, edges [11]Value, edge Edge, defaultValue *Value) (*Value, error) {
+	if edge != EdgeStart && edge != EdgeEnd && edge != EdgeBlockStart && edge != EdgeBlockEnd {
+		return ComputedEdgeValue(edges, edge, defaultValue)
+	}
+	if edges[edge].unit != UnitUndefined {
+		return &edges[edge], nil
+	}
+	shorthand := EdgeVertical
+	if physical := physicalEdge(node, edge); physical == EdgeLeft || physical == EdgeRight {
+		shorthand = EdgeHorizontal
+	}
+	if edges[shorthand].unit != UnitUndefined {
+		return &edges[shorthand], nil
+	}
+	if edges[EdgeAll].unit != UnitUndefined {
+		return &edges[EdgeAll], nil
+	}
+	return &Value{value: math.NaN()}, nil
+}
+
 func ValueResolve(unit *Value, parentSize float64) float64 {
-	if unit.unit == UnitPixel {
+	switch unit.unit {
+	case UnitPixel:
 		return unit.value
+	case UnitPercent:
+		return unit.value * parentSize / 100.0
 	}
-	return unit.value * parentSize / 100.0
+	return math.NaN()
+}
+
+// ValueResolveMargin is like ValueResolve but resolves auto margins to 0, as
+// they only take up space once the free space is distributed.
+func ValueResolveMargin(unit *Value, parentSize float64) float64 {
+	if unit.unit == UnitAuto {
+		return 0
+	}
+	return ValueResolve(unit, parentSize)
 }
 
 func GetChildCount(node *Node) int {
@@ -157,22 +355,40 @@ func InsertChild(node *Node, child *Node, index int) error {
 }
 
 func RemoveChild(node *Node, child *Node) {
-	if listDelete(node.children, child) {
+	var removed bool
+	if node.children, removed = listDelete(node.children, child); removed {
 		child.parent = nil
 		MarkDirtyInternal(node)
 	}
 }
 
-func listDelete(nodes []*Node, item *Node) bool {
+func listDelete(nodes []*Node, item *Node) ([]*Node, bool) {
 	for i := 0; i < len(nodes); i++ {
 		if nodes[i] == item {
 			copy(nodes[i:], nodes[i+1:])
 			nodes[len(nodes)-1] = nil
 			nodes = nodes[:len(nodes)-1]
-			return true
+			return nodes, true
 		}
 	}
-	return false
+	return nodes, false
+}
+
+// LayoutChildren returns the nodes that are laid out as children of node.
+// Children with DisplayNone are left out and children with DisplayContents
+// are replaced by their own layout children, as if those belonged to node.
+func LayoutChildren(node *Node) []*Node {
+	var children []*Node
+	for _, child := range node.children {
+		switch child.style.display {
+		case DisplayNone:
+		case DisplayContents:
+			children = append(children, LayoutChildren(child)...)
+		default:
+			children = append(children, child)
+		}
+	}
+	return children
 }
 
 func GetChild(node *Node, index int) *Node {
@@ -249,6 +465,14 @@ func NodeGetContext(node *Node) *interface{} {
 	return node.context
 }
 
+func SetBaselineFunc(node *Node, baselineFunc BaseLineFunc) {
+	node.baseLine = baselineFunc
+}
+
+func GetBaselineFunc(node *Node) BaseLineFunc {
+	return node.baseLine
+}
+
 func SetPrintFunc(node *Node, printFunc PrintFunc) {
 	node.print = printFunc
 }
@@ -364,6 +588,31 @@ func GetOverflow(node *Node) Overflow {
 	return node.style.overflow
 }
 
+// SetWritingMode sets the writing mode of node. It decides the physical
+// direction of the row (inline) and column (block) axes and of the logical
+// edges of node. Unlike the direction it is not inherited.
+func SetWritingMode(node *Node, writingMode WritingMode) {
+	if node.style.writingMode != writingMode {
+		node.style.writingMode = writingMode
+		MarkDirtyInternal(node)
+	}
+}
+
+func GetWritingMode(node *Node) WritingMode {
+	return node.style.writingMode
+}
+
+func SetDisplay(node *Node, display Display) {
+	if node.style.display != display {
+		node.style.display = display
+		MarkDirtyInternal(node)
+	}
+}
+
+func GetDisplay(node *Node) Display {
+	return node.style.display
+}
+
 func SetFlexGrow(node *Node, flexGrow float64) {
 	if node.style.flexGrow != flexGrow {
 		node.style.flexGrow = flexGrow
@@ -398,6 +647,14 @@ func SetFlexBasisPercent(node *Node, flexBasis float64) {
 	}
 }
 
+func SetFlexBasisAuto(node *Node) {
+	if node.style.flexBasis.unit != UnitAuto {
+		node.style.flexBasis.value = math.NaN()
+		node.style.flexBasis.unit = UnitAuto
+		MarkDirtyInternal(node)
+	}
+}
+
 func SetPosition(node *Node, edge Edge, position float64) {
 	if node.style.position[int(edge)].value != position || node.style.position[int(edge)].unit != UnitPixel {
 		node.style.position[int(edge)].value = position
@@ -419,7 +676,7 @@ func SetPositionPercent(node *Node, edge Edge, position float64) {
 }
 
 func GetPosition(node *Node, edge Edge) (Value, error) {
-	r, err := ComputedEdgeValue(node.style.position, edge, &Value{value: math.NaN()})
+	r, err := styleEdgeValue(node, node.style.position, edge, &Value{value: math.NaN()})
 	if err != nil {
 		return Value{}, err
 	}
@@ -446,8 +703,16 @@ func SetMarginPercent(node *Node, edge Edge, margin float64) {
 	}
 }
 
+func SetMarginAuto(node *Node, edge Edge) {
+	if node.style.margin[int(edge)].unit != UnitAuto {
+		node.style.margin[int(edge)].value = math.NaN()
+		node.style.margin[int(edge)].unit = UnitAuto
+		MarkDirtyInternal(node)
+	}
+}
+
 func GetMargin(node *Node, edge Edge) (Value, error) {
-	r, err := ComputedEdgeValue(node.style.margin, edge, &Value{unit: UnitPixel})
+	r, err := styleEdgeValue(node, node.style.margin, edge, &Value{unit: UnitPixel})
 	if err != nil {
 		return Value{}, err
 	}
@@ -475,7 +740,7 @@ func SetPaddingPercent(node *Node, edge Edge, padding float64) {
 }
 
 func GetPadding(node *Node, edge Edge) (Value, error) {
-	r, err := ComputedEdgeValue(node.style.padding, edge, &Value{unit: UnitPixel})
+	r, err := styleEdgeValue(node, node.style.padding, edge, &Value{unit: UnitPixel})
 	if err != nil {
 		return Value{}, err
 	}
@@ -493,13 +758,42 @@ func SetBorder(node *Node, edge Edge, border float64) {
 }
 
 func GetBorder(node *Node, edge Edge) (float64, error) {
-	r, err := ComputedEdgeValue(node.style.border, edge, &Value{unit: UnitPixel})
+	r, err := styleEdgeValue(node, node.style.border, edge, &Value{unit: UnitPixel})
 	if err != nil {
 		return math.NaN(), err
 	}
 	return r.value, nil
 }
 
+func SetGap(node *Node, gutter Gutter, gap float64) {
+	if node.style.gap[gutter].value != gap || node.style.gap[gutter].unit != UnitPixel {
+		node.style.gap[gutter].value = gap
+		if !math.IsNaN(gap) {
+			node.style.gap[gutter].unit = UnitPixel
+		}
+		MarkDirtyInternal(node)
+	}
+}
+
+func SetGapPercent(node *Node, gutter Gutter, gap float64) {
+	if node.style.gap[gutter].value != gap || node.style.gap[gutter].unit != UnitPercent {
+		node.style.gap[gutter].value = gap
+		if !math.IsNaN(gap) {
+			node.style.gap[gutter].unit = UnitPercent
+		}
+		MarkDirtyInternal(node)
+	}
+}
+
+// GetGap returns the gap set for gutter, falling back to the one set for
+// GutterAll.
+func GetGap(node *Node, gutter Gutter) Value {
+	if node.style.gap[gutter].unit == UnitUndefined {
+		return node.style.gap[GutterAll]
+	}
+	return node.style.gap[gutter]
+}
+
 func SetWidth(node *Node, width float64) {
 	if node.style.dimensions[DimensionWidth].value != width || node.style.dimensions[DimensionWidth].unit != UnitPixel {
 		node.style.dimensions[DimensionWidth].value = width
@@ -520,12 +814,20 @@ func SetWidthPercent(node *Node, width float64) {
 	}
 }
 
+func SetWidthAuto(node *Node) {
+	if node.style.dimensions[DimensionWidth].unit != UnitAuto {
+		node.style.dimensions[DimensionWidth].value = math.NaN()
+		node.style.dimensions[DimensionWidth].unit = UnitAuto
+		MarkDirtyInternal(node)
+	}
+}
+
 func GetStyleWidth(node *Node) Value {
 	return node.style.dimensions[DimensionWidth]
 }
 
 func SetHeight(node *Node, height float64) {
-	if node.style.dimensions[DimensionHeight].value != height || node.style.dimensions[DimensionWidth].unit != UnitPixel {
+	if node.style.dimensions[DimensionHeight].value != height || node.style.dimensions[DimensionHeight].unit != UnitPixel {
 		node.style.dimensions[DimensionHeight].value = height
 		if !math.IsNaN(height) {
 			node.style.dimensions[DimensionHeight].unit = UnitPixel
@@ -544,6 +846,14 @@ func SetHeightPercent(node *Node, height float64) {
 	}
 }
 
+func SetHeightAuto(node *Node) {
+	if node.style.dimensions[DimensionHeight].unit != UnitAuto {
+		node.style.dimensions[DimensionHeight].value = math.NaN()
+		node.style.dimensions[DimensionHeight].unit = UnitAuto
+		MarkDirtyInternal(node)
+	}
+}
+
 func GetHeight(node *Node) Value {
 	return node.style.dimensions[DimensionHeight]
 }
@@ -651,10 +961,17 @@ func SetAspectRatio(node *Node, aspectRatio float64) {
 	}
 }
 
-func GetLayoutAspectRatio(node *Node) float64 {
+func GetAspectRatio(node *Node) float64 {
 	return node.style.aspectRatio
 }
 
+// GetLayoutAspectRatio returns the aspect ratio set on the style of node.
+//
+// Deprecated: Use GetAspectRatio instead.
+func GetLayoutAspectRatio(node *Node) float64 {
+	return GetAspectRatio(node)
+}
+
 func GetLayoutLeft(node *Node) float64 {
 	return node.layout.position[EdgeLeft]
 }
@@ -683,49 +1000,112 @@ func GetLayoutDirection(node *Node) Direction {
 	return node.layout.direction
 }
 
-func GetLayoutMargin(node *Node, edge Edge) (float64, error) {
-	if !(edge <= EdgeEnd) {
+// GetLayoutContentWidth returns the width of the area spanned by the children
+// of a node with OverflowScroll or OverflowHidden, including its padding. It
+// is never less than the width of its padding box.
+func GetLayoutContentWidth(node *Node) float64 {
+	return node.layout.contentDimensions[DimensionWidth]
+}
+
+// GetLayoutContentHeight is like GetLayoutContentWidth for the height.
+func GetLayoutContentHeight(node *Node) float64 {
+	return node.layout.contentDimensions[DimensionHeight]
+}
+
+// GetLayoutHadOverflow reports whether the children of a node with
+// OverflowScroll or OverflowHidden extend past its padding box.
+func GetLayoutHadOverflow(node *Node) bool {
+	return node.layout.hadOverflow
+}
+
+// layoutEdgeValue returns the value for edge in values, which holds the
+// computed margin, padding or border of node with the horizontal edges stored
+// as EdgeStart and EdgeEnd.
+func layoutEdgeValue(node *Node, values [6]float64, edge Edge) (float64, error) {
+	if edgeIsShorthand(edge) {
 		return 0.0, errors.New("Cannot get layout properties of multi-edge shorthands")
 	}
+	edge = physicalEdge(node, edge)
 	if edge == EdgeLeft {
 		if node.layout.direction == DirectionRTL {
-			return node.layout.margin[EdgeEnd], nil
+			return values[EdgeEnd], nil
 		} else {
-			return node.layout.margin[EdgeStart], nil
+			return values[EdgeStart], nil
 		}
 	}
 	if edge == EdgeRight {
 		if node.layout.direction == DirectionRTL {
-			return node.layout.margin[EdgeStart], nil
+			return values[EdgeStart], nil
 		} else {
-			return node.layout.margin[EdgeEnd], nil
+			return values[EdgeEnd], nil
 		}
 	}
-	return node.layout.margin[edge], nil
+	return values[edge], nil
+}
+
+func GetLayoutMargin(node *Node, edge Edge) (float64, error) {
+	return layoutEdgeValue(node, node.layout.margin, edge)
 }
 
 func GetLayoutPadding(node *Node, edge Edge) (float64, error) {
-	if !(edge <= EdgeEnd) {
-		return 0.0, errors.New("Cannot get layout properties of multi-edge shorthands")
-	}
-	if edge == EdgeLeft {
-		if node.layout.direction == DirectionRTL {
-			return node.layout.padding[EdgeEnd], nil
-		} else {
-			return node.layout.padding[EdgeStart], nil
-		}
+	return layoutEdgeValue(node, node.layout.padding, edge)
+}
+
+func GetLayoutBorder(node *Node, edge Edge) (float64, error) {
+	return layoutEdgeValue(node, node.layout.border, edge)
+}
+
+// LayoutEdges holds a computed value for each physical edge of a node.
+type LayoutEdges struct {
+	Left   float64
+	Top    float64
+	Right  float64
+	Bottom float64
+}
+
+// LayoutBox is the complete computed layout of a node: its position relative
+// to its parent, its size, and its margin, border and padding.
+type LayoutBox struct {
+	Left    float64
+	Top     float64
+	Width   float64
+	Height  float64
+	Margin  LayoutEdges
+	Border  LayoutEdges
+	Padding LayoutEdges
+}
+
+// GetLayoutBox returns the computed layout of node in a single LayoutBox.
+func GetLayoutBox(node *Node) LayoutBox {
+	edges := func(get func(*Node, Edge) (float64, error)) LayoutEdges {
+		// Physical edges are always valid, so the errors can be ignored.
+		left, _ := get(node, EdgeLeft)
+		top, _ := get(node, EdgeTop)
+		right, _ := get(node, EdgeRight)
+		bottom, _ := get(node, EdgeBottom)
+		return LayoutEdges{Left: left, Top: top, Right: right, Bottom: bottom}
 	}
-	if edge == EdgeRight {
-		if node.layout.direction == DirectionRTL {
-			return node.layout.padding[EdgeStart], nil
-		} else {
-			return node.layout.padding[EdgeEnd], nil
-		}
+	return LayoutBox{
+		Left:    GetLayoutLeft(node),
+		Top:     GetLayoutTop(node),
+		Width:   GetLayoutWidth(node),
+		Height:  GetLayoutHeight(node),
+		Margin:  edges(GetLayoutMargin),
+		Border:  edges(GetLayoutBorder),
+		Padding: edges(GetLayoutPadding),
 	}
-	return node.layout.padding[edge], nil
 }
 
-var currentGenerationCount uint32
+// generationCounter hands out a distinct generation to every layout pass. It
+// is only ever touched atomically; nodes compare their generation against the
+// one stored in the layoutContext of the pass that is visiting them.
+var generationCounter uint32
+
+// layoutContext carries the state of a single CalculateLayout pass so that
+// independent trees can be laid out concurrently.
+type layoutContext struct {
+	generationCount uint32
+}
 
 func ValueEqual(a Value, b Value) bool {
 	if a.unit != b.unit {
@@ -744,284 +1124,2654 @@ func FloatsEqual(a, b float64) bool {
 	return math.Abs(a-b) < 0.0001
 }
 
-func Indent(n int) {
-	for i := 0; i < n; i++ {
-		log.Println("  ")
-	}
+var leading [4]Edge
+
+func init() {
+	leading[FlexDirectionColumn] = EdgeTop
+	leading[FlexDirectionColumnReverse] = EdgeBottom
+	leading[FlexDirectionRow] = EdgeLeft
+	leading[FlexDirectionRowReverse] = EdgeRight
+}
+
+var trailing [4]Edge
+
+func init() {
+	trailing[FlexDirectionColumn] = EdgeBottom
+	trailing[FlexDirectionColumnReverse] = EdgeTop
+	trailing[FlexDirectionRow] = EdgeRight
+	trailing[FlexDirectionRowReverse] = EdgeLeft
+}
+
+var pos [4]Edge
+
+func init() {
+	pos[FlexDirectionColumn] = EdgeTop
+	pos[FlexDirectionColumnReverse] = EdgeBottom
+	pos[FlexDirectionRow] = EdgeLeft
+	pos[FlexDirectionRowReverse] = EdgeRight
+}
+
+var dim [4]Dimension
+
+func init() {
+	dim[FlexDirectionColumn] = DimensionHeight
+	dim[FlexDirectionColumnReverse] = DimensionHeight
+	dim[FlexDirectionRow] = DimensionWidth
+	dim[FlexDirectionRowReverse] = DimensionWidth
+}
+
+func FlexDirectionIsRow(direction FlexDirection) bool {
+	return direction == FlexDirectionRow || direction == FlexDirectionRowReverse
+}
+
+func FlexDirectionIsColumn(direction FlexDirection) bool {
+	return direction == FlexDirectionColumn || direction == FlexDirectionColumnReverse
 }
 
-func PrintNumberIfNotZero(str string, number *Value) {
-	if !FloatsEqual(number.value, 0) {
-		log.Printf("%s: %g%s, ", str, number.value, number.unit)
+// ResolvedEdgeValue returns the value in edges that applies to the physical
+// edge of node. The logical edges are mapped to physical edges according to
+// the writing mode and layout direction of node and take precedence over
+// them.
+func ResolvedEdgeValue(node *Node, edges [11]Value, edge Edge, defaultValue *Value) (*Value, error) {
+	for _, logicalEdge := range []Edge{EdgeStart, EdgeEnd, EdgeBlockStart, EdgeBlockEnd} {
+		if edge == physicalEdge(node, logicalEdge) && edges[logicalEdge].unit != UnitUndefined {
+			return &edges[logicalEdge], nil
+		}
 	}
+	return ComputedEdgeValue(edges, edge, defaultValue)
 }
 
-func PrintNumberIfNotUndefinedf(str string, number float64) {
-	if !math.IsNaN(number) {
-		log.Printf("%s: %g, ", str, number)
+func LeadingMargin(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.margin, leading[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return 0, err
 	}
+	return ValueResolveMargin(val, widthSize), nil
 }
 
-func PrintNumberIfNotUndefined(str string, number *Value) {
-	if number.unit != UnitUndefined {
-		log.Printf("%s: %g%s, ", str, number.value, number.unit)
+func NodeResolveDirection(node *Node, parentDirection Direction) Direction {
+	if node.style.direction == DirectionInherit {
+		if parentDirection > DirectionInherit {
+			return parentDirection
+		}
+		return DirectionLTR
 	}
+	return node.style.direction
 }
 
-func FourValuesEqual(four [4]Value) bool {
-	return ValueEqual(four[0], four[1]) && ValueEqual(four[0], four[2]) && ValueEqual(four[0], four[3])
+func FlexDirectionResolve(flexDirection FlexDirection, direction Direction) FlexDirection {
+	return FlexDirectionResolveWritingMode(flexDirection, direction, WritingModeHorizontalTB)
 }
 
-func NodePrintInternal(node *Node, options PrintOptions, level int) error {
-	Indent(level)
-	log.Print("{")
-	if node.print != nil {
-		node.print(node)
-	}
-	if options&PrintOptionsLayout != 0 {
-		log.Print("layout: {")
-		log.Print("width: %g, ", node.layout.dimensions[DimensionWidth])
-		log.Print("height: %g, ", node.layout.dimensions[DimensionHeight])
-		log.Print("top: %g, ", node.layout.position[EdgeTop])
-		log.Print("left: %g", node.layout.position[EdgeLeft])
-		log.Print("}, ")
+func FlexDirectionCross(flexDirection FlexDirection, direction Direction) FlexDirection {
+	if FlexDirectionIsColumn(flexDirection) {
+		return FlexDirectionResolve(FlexDirectionRow, direction)
 	}
+	return FlexDirectionColumn
+}
 
-	if options&PrintOptionsStyle != 0 {
-		log.Printf("flexDirection: '%s', ", node.style.flexDirection)
-		log.Printf("justifyContent: '%s', ", node.style.justifyContent)
-		log.Printf("alignItems: '%s', ", node.style.alignItems)
-		log.Printf("alignContent: '%s', ", node.style.alignContent)
-		log.Printf("alignSelf: '%s', ", node.style.alignSelf)
-		PrintNumberIfNotUndefinedf("flexGrow", GetFlexGrow(node))
-		PrintNumberIfNotUndefinedf("flexShrink", GetFlexShrink(node))
-		PrintNumberIfNotUndefined("flexBasis", GetFlexBasisPtr(node))
-		log.Printf("overflow: '%s', ", node.style.overflow)
-		var fourVal [4]Value
-		for i := 0; i < 4; i++ {
-			fourVal[i] = node.style.margin[i]
-		}
-		if FourValuesEqual(fourVal) {
-			val, err := ComputedEdgeValue(node.style.margin, EdgeLeft, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("margin", val)
-		} else {
-			val, err := ComputedEdgeValue(node.style.margin, EdgeLeft, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("marginLeft", val)
-			val, err = ComputedEdgeValue(node.style.margin, EdgeRight, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("marginRight", val)
-			val, err = ComputedEdgeValue(node.style.margin, EdgeTop, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("marginTop", val)
-			val, err = ComputedEdgeValue(node.style.margin, EdgeBottom, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("marginBottom", val)
-			val, err = ComputedEdgeValue(node.style.margin, EdgeStart, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("marginStart", val)
-			val, err = ComputedEdgeValue(node.style.margin, EdgeEnd, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("marginEnd", val)
-		}
-		for i := 0; i < 4; i++ {
-			fourVal[i] = node.style.border[i]
-		}
-		if FourValuesEqual(fourVal) {
-			val, err := ComputedEdgeValue(node.style.border, EdgeLeft, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
+// FlexDirectionResolveWritingMode returns the physical axis that
+// flexDirection runs along in writingMode. The row axis is the inline axis,
+// reversed by DirectionRTL, and the column axis is the block axis.
+func FlexDirectionResolveWritingMode(flexDirection FlexDirection, direction Direction,
+	writingMode WritingMode) FlexDirection {
+	if writingMode == WritingModeHorizontalTB {
+		if direction == DirectionRTL {
+			if flexDirection == FlexDirectionRow {
+				return FlexDirectionRowReverse
+			} else if flexDirection == FlexDirectionRowReverse {
+				return FlexDirectionRow
 			}
-			PrintNumberIfNotZero("borderWidth", val)
-		} else {
-			val, err := ComputedEdgeValue(node.style.border, EdgeLeft, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("borderLeftWidth", val)
-			val, err = ComputedEdgeValue(node.style.border, EdgeRight, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("borderRightWidth", val)
-			val, err = ComputedEdgeValue(node.style.border, EdgeTop, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("borderTopWidth", val)
-			val, err = ComputedEdgeValue(node.style.border, EdgeBottom, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("borderBottomWidth", val)
-			val, err = ComputedEdgeValue(node.style.border, EdgeStart, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("borderStartWidth", val)
-			val, err = ComputedEdgeValue(node.style.border, EdgeEnd, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("borderEndWidth", val)
 		}
-		for i := 0; i < 4; i++ {
-			fourVal[i] = node.style.padding[i]
+		return flexDirection
+	}
+
+	// In the vertical writing modes lines of text run from top to bottom and
+	// follow each other from right to left or from left to right.
+	if FlexDirectionIsRow(flexDirection) {
+		if (flexDirection == FlexDirectionRowReverse) != (direction == DirectionRTL) {
+			return FlexDirectionColumnReverse
 		}
-		if FourValuesEqual(fourVal) {
-			val, err := ComputedEdgeValue(node.style.padding, EdgeLeft, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("padding", val)
-		} else {
-			val, err := ComputedEdgeValue(node.style.padding, EdgeLeft, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("paddingLeft", val)
-			val, err = ComputedEdgeValue(node.style.padding, EdgeRight, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
-				return err
-			}
-			PrintNumberIfNotZero("paddingRight", val)
-			val, err = ComputedEdgeValue(node.style.padding, EdgeTop, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
+		return FlexDirectionColumn
+	}
+	if (flexDirection == FlexDirectionColumnReverse) != (writingMode == WritingModeVerticalRL) {
+		return FlexDirectionRowReverse
+	}
+	return FlexDirectionRow
+}
+
+// nodeMainAxis returns the physical axis along which node lays out its
+// children.
+func nodeMainAxis(node *Node, direction Direction) FlexDirection {
+	return FlexDirectionResolveWritingMode(node.style.flexDirection, direction, node.style.writingMode)
+}
+
+// nodeCrossAxis returns the physical axis perpendicular to the main axis of
+// node.
+func nodeCrossAxis(node *Node, direction Direction) FlexDirection {
+	if FlexDirectionIsRow(node.style.flexDirection) {
+		return FlexDirectionResolveWritingMode(FlexDirectionColumn, direction, node.style.writingMode)
+	}
+	return FlexDirectionResolveWritingMode(FlexDirectionRow, direction, node.style.writingMode)
+}
+
+func FloatMax(a, b float64) float64 {
+	if math.IsNaN(a) {
+		return b
+	}
+	if math.IsNaN(b) {
+		return a
+	}
+	return math.Max(a, b)
+}
+
+func FloatMin(a, b float64) float64 {
+	if math.IsNaN(a) {
+		return b
+	}
+	if math.IsNaN(b) {
+		return a
+	}
+	return math.Min(a, b)
+}
+
+func TrailingMargin(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.margin, trailing[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return 0, err
+	}
+	return ValueResolveMargin(val, widthSize), nil
+}
+
+func LeadingPadding(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.padding, leading[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return 0, err
+	}
+	return FloatMax(ValueResolve(val, widthSize), 0.0), nil
+}
+
+func TrailingPadding(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.padding, trailing[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return 0, err
+	}
+	return FloatMax(ValueResolve(val, widthSize), 0.0), nil
+}
+
+func LeadingBorder(node *Node, axis FlexDirection) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.border, leading[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return 0, err
+	}
+	return FloatMax(val.value, 0.0), nil
+}
+
+func TrailingBorder(node *Node, axis FlexDirection) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.border, trailing[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return 0, err
+	}
+	return FloatMax(val.value, 0.0), nil
+}
+
+func LeadingPaddingAndBorder(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	padding, err := LeadingPadding(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	border, err := LeadingBorder(node, axis)
+	if err != nil {
+		return 0, err
+	}
+	return padding + border, nil
+}
+
+func TrailingPaddingAndBorder(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	padding, err := TrailingPadding(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	border, err := TrailingBorder(node, axis)
+	if err != nil {
+		return 0, err
+	}
+	return padding + border, nil
+}
+
+// AutoMargins reports whether the leading and trailing margins of node along
+// axis are auto.
+func AutoMargins(node *Node, axis FlexDirection) (bool, bool, error) {
+	leadingMargin, err := ResolvedEdgeValue(node, node.style.margin, leading[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return false, false, err
+	}
+	trailingMargin, err := ResolvedEdgeValue(node, node.style.margin, trailing[axis], &Value{value: 0, unit: UnitPixel})
+	if err != nil {
+		return false, false, err
+	}
+	return leadingMargin.unit == UnitAuto, trailingMargin.unit == UnitAuto, nil
+}
+
+// IsStretched reports whether child is stretched along the cross axis of
+// node. Auto margins on that axis take precedence over AlignStretch.
+func IsStretched(node *Node, child *Node, crossAxis FlexDirection) (bool, error) {
+	if AlignItem(node, child) != AlignStretch {
+		return false, nil
+	}
+	leadingAuto, trailingAuto, err := AutoMargins(child, crossAxis)
+	if err != nil {
+		return false, err
+	}
+	return !leadingAuto && !trailingAuto, nil
+}
+
+func MarginForAxis(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	leadingMargin, err := LeadingMargin(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	trailingMargin, err := TrailingMargin(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	return leadingMargin + trailingMargin, nil
+}
+
+// GapForAxis returns the space between consecutive items along axis: the
+// column gap for a row axis and the row gap for a column axis. Percentages
+// resolve against axisSize.
+func GapForAxis(node *Node, axis FlexDirection, axisSize float64) float64 {
+	// Columns are separated along the inline axis.
+	gutter := GutterRow
+	if FlexDirectionIsRow(axis) == (node.style.writingMode == WritingModeHorizontalTB) {
+		gutter = GutterColumn
+	}
+	gap := GetGap(node, gutter)
+	return FloatMax(ValueResolve(&gap, axisSize), 0.0)
+}
+
+func PaddingAndBorderForAxis(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	leadingPaddingAndBorder, err := LeadingPaddingAndBorder(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	trailingPaddingAndBorder, err := TrailingPaddingAndBorder(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	return leadingPaddingAndBorder + trailingPaddingAndBorder, nil
+}
+
+func AlignItem(node *Node, child *Node) Align {
+	align := child.style.alignSelf
+	if align == AlignAuto {
+		align = node.style.alignItems
+	}
+	if align == AlignBaseLine && FlexDirectionIsColumn(node.style.flexDirection) {
+		return AlignFlexStart
+	}
+	return align
+}
+
+// Baseline returns the distance from the top of node to its baseline. It
+// uses the baseline function of the node if one is set and otherwise falls
+// back to the baseline of its first baseline-aligned child, or of its first
+// child, on the first line. A node without children uses its own height.
+func Baseline(node *Node) (float64, error) {
+	if node.baseLine != nil {
+		baseline := node.baseLine(node, node.layout.measuredDimensions[DimensionWidth], node.layout.measuredDimensions[DimensionHeight])
+		if math.IsNaN(baseline) {
+			return 0, errors.New("Expect custom baseline function to not return NaN")
+		}
+		return baseline, nil
+	}
+
+	var baselineChild *Node
+	for _, child := range LayoutChildren(node) {
+		if child.lineIndex > 0 {
+			break
+		}
+		if child.style.positionType == PositionTypeAbsolute {
+			continue
+		}
+		if AlignItem(node, child) == AlignBaseLine {
+			baselineChild = child
+			break
+		}
+		if baselineChild == nil {
+			baselineChild = child
+		}
+	}
+
+	if baselineChild == nil {
+		return node.layout.measuredDimensions[DimensionHeight], nil
+	}
+
+	baseline, err := Baseline(baselineChild)
+	if err != nil {
+		return 0, err
+	}
+	return baseline + baselineChild.layout.position[EdgeTop], nil
+}
+
+func IsBaselineLayout(node *Node) bool {
+	if FlexDirectionIsColumn(node.style.flexDirection) {
+		return false
+	}
+	if node.style.alignItems == AlignBaseLine {
+		return true
+	}
+	for _, child := range LayoutChildren(node) {
+		if child.style.positionType != PositionTypeAbsolute && child.style.alignSelf == AlignBaseLine {
+			return true
+		}
+	}
+	return false
+}
+
+// baselineAscentAndDescent returns the extent of child above and below its
+// baseline, margins included.
+func baselineAscentAndDescent(child *Node, widthSize float64) (float64, float64, error) {
+	baseline, err := Baseline(child)
+	if err != nil {
+		return 0, 0, err
+	}
+	leadingMargin, err := LeadingMargin(child, FlexDirectionColumn, widthSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	marginColumn, err := MarginForAxis(child, FlexDirectionColumn, widthSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	ascent := baseline + leadingMargin
+	descent := child.layout.measuredDimensions[DimensionHeight] + marginColumn - ascent
+	return ascent, descent, nil
+}
+
+func IsFlex(node *Node) bool {
+	return node.style.positionType != PositionTypeAbsolute &&
+		(GetFlexGrow(node) != 0 || GetFlexShrink(node) != 0)
+}
+
+func DimWithMargin(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
+	margin, err := MarginForAxis(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	return node.layout.measuredDimensions[dim[axis]] + margin, nil
+}
+
+func IsStyleDimDefined(node *Node, axis FlexDirection, parentSize float64) bool {
+	value := node.style.dimensions[dim[axis]]
+	return !(value.unit == UnitUndefined || value.unit == UnitAuto ||
+		(value.unit == UnitPixel && value.value < 0.0) ||
+		(value.unit == UnitPercent && (value.value < 0.0 || math.IsNaN(parentSize))))
+}
+
+func IsLayoutDimDefined(node *Node, axis FlexDirection) bool {
+	value := node.layout.measuredDimensions[dim[axis]]
+	return !math.IsNaN(value) && value >= 0.0
+}
+
+func IsLeadingPosDefined(node *Node, axis FlexDirection) (bool, error) {
+	val, err := ResolvedEdgeValue(node, node.style.position, leading[axis], &Value{value: math.NaN()})
+	if err != nil {
+		return false, err
+	}
+	return val.unit != UnitUndefined, nil
+}
+
+func IsTrailingPosDefined(node *Node, axis FlexDirection) (bool, error) {
+	val, err := ResolvedEdgeValue(node, node.style.position, trailing[axis], &Value{value: math.NaN()})
+	if err != nil {
+		return false, err
+	}
+	return val.unit != UnitUndefined, nil
+}
+
+func LeadingPosition(node *Node, axis FlexDirection, axisSize float64) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.position, leading[axis], &Value{value: math.NaN()})
+	if err != nil {
+		return 0, err
+	}
+	if val.unit == UnitUndefined {
+		return 0.0, nil
+	}
+	return ValueResolve(val, axisSize), nil
+}
+
+func TrailingPosition(node *Node, axis FlexDirection, axisSize float64) (float64, error) {
+	val, err := ResolvedEdgeValue(node, node.style.position, trailing[axis], &Value{value: math.NaN()})
+	if err != nil {
+		return 0, err
+	}
+	if val.unit == UnitUndefined {
+		return 0.0, nil
+	}
+	return ValueResolve(val, axisSize), nil
+}
+
+func BoundAxisWithinMinAndMax(node *Node, axis FlexDirection, value, axisSize float64) float64 {
+	min := math.NaN()
+	max := math.NaN()
+	if FlexDirectionIsColumn(axis) {
+		min = ValueResolve(&node.style.minDimensions[DimensionHeight], axisSize)
+		max = ValueResolve(&node.style.maxDimensions[DimensionHeight], axisSize)
+	} else if FlexDirectionIsRow(axis) {
+		min = ValueResolve(&node.style.minDimensions[DimensionWidth], axisSize)
+		max = ValueResolve(&node.style.maxDimensions[DimensionWidth], axisSize)
+	}
+	boundValue := value
+	if !math.IsNaN(max) && max >= 0.0 && boundValue > max {
+		boundValue = max
+	}
+	if !math.IsNaN(min) && min >= 0.0 && boundValue < min {
+		boundValue = min
+	}
+	return boundValue
+}
+
+// ConstrainMaxSizeForMode caps the available size of node along axis, margin
+// included, to its max dimension. An undefined size becomes an at-most one.
+func ConstrainMaxSizeForMode(node *Node, axis FlexDirection, parentAxisSize, parentWidth float64, mode MeasureMode,
+	size float64) (MeasureMode, float64, error) {
+	margin, err := MarginForAxis(node, axis, parentWidth)
+	if err != nil {
+		return mode, size, err
+	}
+	maxSize := ValueResolve(&node.style.maxDimensions[dim[axis]], parentAxisSize) + margin
+	if math.IsNaN(maxSize) {
+		return mode, size, nil
+	}
+	switch mode {
+	case MeasureModeExactly, MeasureModeAtmost:
+		return mode, FloatMin(size, maxSize), nil
+	}
+	return MeasureModeAtmost, maxSize, nil
+}
+
+// ResolveFlexibleLengths returns the main sizes of the items of a line, in
+// the order of the list starting at firstRelativeChild, once
+// remainingFreeSpace is distributed among them following their flex factors.
+// Items whose size violates their min or max dimensions are frozen at the
+// violated bound and the free space is distributed again among the other
+// items until no violation is left.
+func ResolveFlexibleLengths(firstRelativeChild *Node, mainAxis FlexDirection, remainingFreeSpace, availableInnerMainDim,
+	availableInnerWidth float64) ([]float64, error) {
+	var items []*Node
+	for child := firstRelativeChild; child != nil; child = child.nextChild {
+		items = append(items, child)
+	}
+
+	isGrowing := remainingFreeSpace > 0
+	flexBases := make([]float64, len(items))
+	mainSizes := make([]float64, len(items))
+	violations := make([]float64, len(items))
+	frozen := make([]bool, len(items))
+	for i, child := range items {
+		flexBases[i] = BoundAxisWithinMinAndMax(child, mainAxis, child.layout.computedFlexBasis, availableInnerMainDim)
+		mainSizes[i] = flexBases[i]
+
+		// Items that can't flex in the needed direction keep their
+		// hypothetical main size.
+		frozen[i] = remainingFreeSpace == 0 || !IsFlex(child) ||
+			(isGrowing && GetFlexGrow(child) == 0) || (!isGrowing && GetFlexShrink(child) == 0)
+	}
+
+	for {
+		freeSpace := remainingFreeSpace
+		totalFlexGrowFactors := 0.0
+		totalFlexShrinkScaledFactors := 0.0
+		unfrozenItems := 0
+		for i, child := range items {
+			if frozen[i] {
+				freeSpace -= mainSizes[i] - flexBases[i]
+				continue
+			}
+			unfrozenItems++
+			totalFlexGrowFactors += GetFlexGrow(child)
+
+			// Unlike the grow factor, the shrink factor is scaled relative to
+			// the child dimension.
+			totalFlexShrinkScaledFactors += GetFlexShrink(child) * flexBases[i]
+		}
+		if unfrozenItems == 0 {
+			break
+		}
+
+		totalViolation := 0.0
+		for i, child := range items {
+			if frozen[i] {
+				continue
+			}
+			mainSize := flexBases[i]
+			if isGrowing && totalFlexGrowFactors > 0 {
+				mainSize += freeSpace / totalFlexGrowFactors * GetFlexGrow(child)
+			} else if !isGrowing && totalFlexShrinkScaledFactors > 0 {
+				mainSize += freeSpace / totalFlexShrinkScaledFactors * GetFlexShrink(child) * flexBases[i]
+			}
+			boundMainSize, err := BoundAxis(child, mainAxis, mainSize, availableInnerMainDim, availableInnerWidth)
+			if err != nil {
+				return nil, err
+			}
+			mainSizes[i] = boundMainSize
+			violations[i] = boundMainSize - mainSize
+			totalViolation += violations[i]
+		}
+
+		// Freeze every item when nothing was clamped, otherwise only the
+		// items clamped in the same direction as the total violation.
+		for i := range items {
+			if !frozen[i] && (FloatsEqual(totalViolation, 0) || violations[i]*totalViolation > 0) {
+				frozen[i] = true
+			}
+		}
+	}
+	return mainSizes, nil
+}
+
+// BoundAxis is like BoundAxisWithinMinAndMax but also ensures that the value
+// doesn't go below the padding and border amount.
+func BoundAxis(node *Node, axis FlexDirection, value, axisSize, widthSize float64) (float64, error) {
+	paddingAndBorder, err := PaddingAndBorderForAxis(node, axis, widthSize)
+	if err != nil {
+		return 0, err
+	}
+	return FloatMax(BoundAxisWithinMinAndMax(node, axis, value, axisSize), paddingAndBorder), nil
+}
+
+func HasAspectRatio(node *Node) bool {
+	return !math.IsNaN(node.style.aspectRatio) && node.style.aspectRatio > 0.0
+}
+
+// AspectRatioSize returns the size of node across axis implied by its aspect
+// ratio (width / height) when its size along axis is size, clamped to the
+// min and max dimensions across axis. Both sizes exclude margins.
+func AspectRatioSize(node *Node, axis FlexDirection, size, crossAxisParentSize, widthSize float64) (float64, error) {
+	if FlexDirectionIsRow(axis) {
+		return BoundAxis(node, FlexDirectionColumn, size/node.style.aspectRatio, crossAxisParentSize, widthSize)
+	}
+	return BoundAxis(node, FlexDirectionRow, size*node.style.aspectRatio, crossAxisParentSize, widthSize)
+}
+
+func SetChildTrailingPosition(node *Node, child *Node, axis FlexDirection) {
+	size := child.layout.measuredDimensions[dim[axis]]
+	child.layout.position[trailing[axis]] = node.layout.measuredDimensions[dim[axis]] - size - child.layout.position[pos[axis]]
+}
+
+// RelativePosition returns +leading or -trailing depending on which is
+// defined. If both are defined the leading one wins. Static nodes ignore
+// their insets.
+func RelativePosition(node *Node, axis FlexDirection, axisSize float64) (float64, error) {
+	if node.style.positionType == PositionTypeStatic {
+		return 0, nil
+	}
+	isLeadingPosDefined, err := IsLeadingPosDefined(node, axis)
+	if err != nil {
+		return 0, err
+	}
+	if isLeadingPosDefined {
+		return LeadingPosition(node, axis, axisSize)
+	}
+	trailingPosition, err := TrailingPosition(node, axis, axisSize)
+	if err != nil {
+		return 0, err
+	}
+	return -trailingPosition, nil
+}
+
+func NodeSetPosition(node *Node, direction Direction, mainSize, crossSize, parentWidth float64) error {
+	// The root has no parent to derive its left edge from a trailing
+	// position, so it is always positioned from the left.
+	mainAxis := nodeMainAxis(node, direction)
+	crossAxis := nodeCrossAxis(node, direction)
+	if node.parent == nil {
+		mainAxis = FlexDirectionResolve(node.style.flexDirection, DirectionLTR)
+		crossAxis = FlexDirectionCross(mainAxis, DirectionLTR)
+	}
+	relativePositionMain, err := RelativePosition(node, mainAxis, mainSize)
+	if err != nil {
+		return err
+	}
+	relativePositionCross, err := RelativePosition(node, crossAxis, crossSize)
+	if err != nil {
+		return err
+	}
+	leadingMainMargin, err := LeadingMargin(node, mainAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	trailingMainMargin, err := TrailingMargin(node, mainAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	leadingCrossMargin, err := LeadingMargin(node, crossAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	trailingCrossMargin, err := TrailingMargin(node, crossAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	node.layout.position[leading[mainAxis]] = leadingMainMargin + relativePositionMain
+	node.layout.position[trailing[mainAxis]] = trailingMainMargin + relativePositionMain
+	node.layout.position[leading[crossAxis]] = leadingCrossMargin + relativePositionCross
+	node.layout.position[trailing[crossAxis]] = trailingCrossMargin + relativePositionCross
+	return nil
+}
+
+func ComputeFlexBasisForChild(ctx *layoutContext, node *Node, child *Node, width float64, widthMode MeasureMode, height, parentWidth, parentHeight float64,
+	heightMode MeasureMode, direction Direction) error {
+	mainAxis := nodeMainAxis(node, direction)
+	isMainAxisRow := FlexDirectionIsRow(mainAxis)
+	mainAxisSize := height
+	mainAxisParentSize := parentHeight
+	if isMainAxisRow {
+		mainAxisSize = width
+		mainAxisParentSize = parentWidth
+	}
+
+	resolvedFlexBasis := ValueResolve(GetFlexBasisPtr(child), mainAxisParentSize)
+	isRowStyleDimDefined := IsStyleDimDefined(child, FlexDirectionRow, parentWidth)
+	isColumnStyleDimDefined := IsStyleDimDefined(child, FlexDirectionColumn, parentHeight)
+
+	if !math.IsNaN(resolvedFlexBasis) && !math.IsNaN(mainAxisSize) {
+		if math.IsNaN(child.layout.computedFlexBasis) {
+			paddingAndBorder, err := PaddingAndBorderForAxis(child, mainAxis, parentWidth)
+			if err != nil {
+				return err
+			}
+			child.layout.computedFlexBasis = FloatMax(resolvedFlexBasis, paddingAndBorder)
+		}
+	} else if isMainAxisRow && isRowStyleDimDefined {
+		// The width is definite, so use that as the flex basis.
+		paddingAndBorder, err := PaddingAndBorderForAxis(child, FlexDirectionRow, parentWidth)
+		if err != nil {
+			return err
+		}
+		child.layout.computedFlexBasis = FloatMax(ValueResolve(&child.style.dimensions[DimensionWidth], parentWidth), paddingAndBorder)
+	} else if !isMainAxisRow && isColumnStyleDimDefined {
+		// The height is definite, so use that as the flex basis.
+		paddingAndBorder, err := PaddingAndBorderForAxis(child, FlexDirectionColumn, parentWidth)
+		if err != nil {
+			return err
+		}
+		child.layout.computedFlexBasis = FloatMax(ValueResolve(&child.style.dimensions[DimensionHeight], parentHeight), paddingAndBorder)
+	} else {
+		// Compute the flex basis and hypothetical main size (i.e. the clamped
+		// flex basis).
+		childWidth := math.NaN()
+		childHeight := math.NaN()
+		childWidthMeasureMode := MeasureModeUndefined
+		childHeightMeasureMode := MeasureModeUndefined
+
+		marginRow, err := MarginForAxis(child, FlexDirectionRow, parentWidth)
+		if err != nil {
+			return err
+		}
+		marginColumn, err := MarginForAxis(child, FlexDirectionColumn, parentWidth)
+		if err != nil {
+			return err
+		}
+
+		if isRowStyleDimDefined {
+			childWidth = ValueResolve(&child.style.dimensions[DimensionWidth], parentWidth) + marginRow
+			childWidthMeasureMode = MeasureModeExactly
+		}
+		if isColumnStyleDimDefined {
+			childHeight = ValueResolve(&child.style.dimensions[DimensionHeight], parentHeight) + marginColumn
+			childHeightMeasureMode = MeasureModeExactly
+		}
+
+		// A scroll container doesn't constrain its children along its main
+		// axis, which is the one it scrolls along.
+		isScrollContainer := node.style.overflow == OverflowScroll
+		if math.IsNaN(childWidth) && !math.IsNaN(width) && !(isMainAxisRow && isScrollContainer) {
+			childWidth = width
+			childWidthMeasureMode = MeasureModeAtmost
+		}
+		if math.IsNaN(childHeight) && !math.IsNaN(height) && !(!isMainAxisRow && isScrollContainer) {
+			childHeight = height
+			childHeightMeasureMode = MeasureModeAtmost
+		}
+
+		// A definite cross size determines the main size through the aspect
+		// ratio.
+		if HasAspectRatio(child) {
+			if !isMainAxisRow && childWidthMeasureMode == MeasureModeExactly {
+				if childHeight, err = AspectRatioSize(child, FlexDirectionRow, childWidth-marginRow, parentHeight, parentWidth); err != nil {
+					return err
+				}
+				childHeight += marginColumn
+				childHeightMeasureMode = MeasureModeExactly
+			} else if isMainAxisRow && childHeightMeasureMode == MeasureModeExactly {
+				if childWidth, err = AspectRatioSize(child, FlexDirectionColumn, childHeight-marginColumn, parentWidth, parentWidth); err != nil {
+					return err
+				}
+				childWidth += marginRow
+				childWidthMeasureMode = MeasureModeExactly
+			}
+		}
+
+		// If child has no defined size in the cross axis and is set to stretch,
+		// set the cross axis to be measured exactly with the available inner
+		// width.
+		isStretched, err := IsStretched(node, child, nodeCrossAxis(node, direction))
+		if err != nil {
+			return err
+		}
+		if !isMainAxisRow && !math.IsNaN(width) && !isRowStyleDimDefined &&
+			widthMode == MeasureModeExactly && isStretched && childWidthMeasureMode != MeasureModeExactly {
+			childWidth = width
+			childWidthMeasureMode = MeasureModeExactly
+			if HasAspectRatio(child) {
+				if childHeight, err = AspectRatioSize(child, FlexDirectionRow, childWidth-marginRow, parentHeight, parentWidth); err != nil {
+					return err
+				}
+				childHeight += marginColumn
+				childHeightMeasureMode = MeasureModeExactly
+			}
+		}
+		if isMainAxisRow && !math.IsNaN(height) && !isColumnStyleDimDefined &&
+			heightMode == MeasureModeExactly && isStretched && childHeightMeasureMode != MeasureModeExactly {
+			childHeight = height
+			childHeightMeasureMode = MeasureModeExactly
+			if HasAspectRatio(child) {
+				if childWidth, err = AspectRatioSize(child, FlexDirectionColumn, childHeight-marginColumn, parentWidth, parentWidth); err != nil {
+					return err
+				}
+				childWidth += marginRow
+				childWidthMeasureMode = MeasureModeExactly
+			}
+		}
+
+		if childWidthMeasureMode, childWidth, err = ConstrainMaxSizeForMode(child, FlexDirectionRow, parentWidth, parentWidth,
+			childWidthMeasureMode, childWidth); err != nil {
+			return err
+		}
+		if childHeightMeasureMode, childHeight, err = ConstrainMaxSizeForMode(child, FlexDirectionColumn, parentHeight, parentWidth,
+			childHeightMeasureMode, childHeight); err != nil {
+			return err
+		}
+
+		// Measure the child
+		if _, err := LayoutNodeInternal(ctx, child, childWidth, childHeight, direction, childWidthMeasureMode, childHeightMeasureMode,
+			parentWidth, parentHeight, false); err != nil {
+			return err
+		}
+
+		paddingAndBorder, err := PaddingAndBorderForAxis(child, mainAxis, parentWidth)
+		if err != nil {
+			return err
+		}
+		child.layout.computedFlexBasis = FloatMax(child.layout.measuredDimensions[dim[mainAxis]], paddingAndBorder)
+	}
+
+	child.layout.computedFlexBasisGeneration = ctx.generationCount
+	return nil
+}
+
+// AbsoluteLayoutChild sizes and positions an absolutely positioned child
+// once the size of node is known. Insets, percentages and the size implied by
+// two opposing insets are resolved against the padding box of node. On an
+// axis without insets the child is aligned within that padding box following
+// justifyContent on the main axis and alignItems or alignSelf on the cross
+// axis.
+func AbsoluteLayoutChild(ctx *layoutContext, node *Node, child *Node, widthMode MeasureMode, direction Direction) error {
+	mainAxis := nodeMainAxis(node, direction)
+	crossAxis := nodeCrossAxis(node, direction)
+	isMainAxisRow := FlexDirectionIsRow(mainAxis)
+
+	width, err := paddingBoxSize(node, FlexDirectionRow)
+	if err != nil {
+		return err
+	}
+	height, err := paddingBoxSize(node, FlexDirectionColumn)
+	if err != nil {
+		return err
+	}
+
+	childWidth := math.NaN()
+	childHeight := math.NaN()
+	childWidthMeasureMode := MeasureModeUndefined
+	childHeightMeasureMode := MeasureModeUndefined
+
+	marginRow, err := MarginForAxis(child, FlexDirectionRow, width)
+	if err != nil {
+		return err
+	}
+	marginColumn, err := MarginForAxis(child, FlexDirectionColumn, width)
+	if err != nil {
+		return err
+	}
+
+	if IsStyleDimDefined(child, FlexDirectionRow, width) {
+		childWidth = ValueResolve(&child.style.dimensions[DimensionWidth], width) + marginRow
+	} else {
+		// If the child doesn't have a specified width, compute the width based
+		// on the left/right offsets if they're defined.
+		childWidth, err = absoluteChildSizeFromInsets(child, FlexDirectionRow, width, width)
+		if err != nil {
+			return err
+		}
+	}
+
+	if IsStyleDimDefined(child, FlexDirectionColumn, height) {
+		childHeight = ValueResolve(&child.style.dimensions[DimensionHeight], height) + marginColumn
+	} else {
+		// If the child doesn't have a specified height, compute the height
+		// based on the top/bottom offsets if they're defined.
+		childHeight, err = absoluteChildSizeFromInsets(child, FlexDirectionColumn, height, width)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Exactly one dimension needs to be defined to derive the other one from
+	// the aspect ratio.
+	if HasAspectRatio(child) && math.IsNaN(childWidth) != math.IsNaN(childHeight) {
+		if math.IsNaN(childWidth) {
+			if childWidth, err = AspectRatioSize(child, FlexDirectionColumn, childHeight-marginColumn, width, width); err != nil {
+				return err
+			}
+			childWidth += marginRow
+		} else {
+			if childHeight, err = AspectRatioSize(child, FlexDirectionRow, childWidth-marginRow, height, width); err != nil {
+				return err
+			}
+			childHeight += marginColumn
+		}
+	}
+
+	// If we're still missing one or the other dimension, measure the content.
+	if math.IsNaN(childWidth) || math.IsNaN(childHeight) {
+		if !math.IsNaN(childWidth) {
+			childWidthMeasureMode = MeasureModeExactly
+		}
+		if !math.IsNaN(childHeight) {
+			childHeightMeasureMode = MeasureModeExactly
+		}
+
+		// If the size of the parent is defined then try to constrain the
+		// absolute child to that size as well. This allows text within the
+		// absolute child to wrap to the size of its parent. This is the same
+		// behavior as many browsers implement.
+		if !isMainAxisRow && math.IsNaN(childWidth) && widthMode != MeasureModeUndefined && width > 0 {
+			childWidth = width
+			childWidthMeasureMode = MeasureModeAtmost
+		}
+
+		if _, err := LayoutNodeInternal(ctx, child, childWidth, childHeight, direction, childWidthMeasureMode, childHeightMeasureMode,
+			width, height, false); err != nil {
+			return err
+		}
+		childWidth = child.layout.measuredDimensions[DimensionWidth] + marginRow
+		childHeight = child.layout.measuredDimensions[DimensionHeight] + marginColumn
+	}
+
+	if _, err := LayoutNodeInternal(ctx, child, childWidth, childHeight, direction, MeasureModeExactly, MeasureModeExactly,
+		width, height, true); err != nil {
+		return err
+	}
+
+	for _, axis := range []FlexDirection{mainAxis, crossAxis} {
+		axisSize := height
+		if FlexDirectionIsRow(axis) {
+			axisSize = width
+		}
+		isLeadingPosDefined, err := IsLeadingPosDefined(child, axis)
+		if err != nil {
+			return err
+		}
+		isTrailingPosDefined, err := IsTrailingPosDefined(child, axis)
+		if err != nil {
+			return err
+		}
+		leadingBorder, err := LeadingBorder(node, axis)
+		if err != nil {
+			return err
+		}
+		leadingMargin, err := LeadingMargin(child, axis, width)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case isLeadingPosDefined:
+			leadingPosition, err := LeadingPosition(child, axis, axisSize)
+			if err != nil {
+				return err
+			}
+			child.layout.position[leading[axis]] = leadingBorder + leadingPosition + leadingMargin
+		case isTrailingPosDefined:
+			trailingBorder, err := TrailingBorder(node, axis)
+			if err != nil {
+				return err
+			}
+			trailingMargin, err := TrailingMargin(child, axis, width)
+			if err != nil {
+				return err
+			}
+			trailingPosition, err := TrailingPosition(child, axis, axisSize)
+			if err != nil {
+				return err
+			}
+			child.layout.position[leading[axis]] = node.layout.measuredDimensions[dim[axis]] -
+				child.layout.measuredDimensions[dim[axis]] - trailingBorder - trailingMargin - trailingPosition
+		default:
+			// Without insets the child is aligned within the content box of
+			// the parent.
+			leadingPadding, err := GetLayoutPadding(node, leading[axis])
+			if err != nil {
+				return err
+			}
+			trailingPadding, err := GetLayoutPadding(node, trailing[axis])
+			if err != nil {
+				return err
+			}
+			marginAxis, err := MarginForAxis(child, axis, width)
+			if err != nil {
+				return err
+			}
+			freeSpace := axisSize - leadingPadding - trailingPadding - child.layout.measuredDimensions[dim[axis]] - marginAxis
+			offset := 0.0
+			if axis == mainAxis {
+				switch node.style.justifyContent {
+				case JustifyCenter:
+					offset = freeSpace / 2
+				case JustifyFlexEnd:
+					offset = freeSpace
+				}
+			} else {
+				switch AlignItem(node, child) {
+				case AlignCenter:
+					offset = freeSpace / 2
+				case AlignFlexEnd:
+					offset = freeSpace
+				}
+			}
+			child.layout.position[leading[axis]] = leadingBorder + leadingPadding + leadingMargin + offset
+		}
+	}
+	return nil
+}
+
+// paddingBoxSize returns the size of the padding box of node along axis.
+func paddingBoxSize(node *Node, axis FlexDirection) (float64, error) {
+	leadingBorder, err := LeadingBorder(node, axis)
+	if err != nil {
+		return 0, err
+	}
+	trailingBorder, err := TrailingBorder(node, axis)
+	if err != nil {
+		return 0, err
+	}
+	return node.layout.measuredDimensions[dim[axis]] - leadingBorder - trailingBorder, nil
+}
+
+// absoluteChildSizeFromInsets returns the outer size of an absolutely
+// positioned child along axis when both of its insets on that axis are
+// defined, and NaN otherwise. axisSize is the size of the padding box of the
+// parent along axis.
+func absoluteChildSizeFromInsets(child *Node, axis FlexDirection, axisSize, widthSize float64) (float64, error) {
+	isLeadingPosDefined, err := IsLeadingPosDefined(child, axis)
+	if err != nil {
+		return 0, err
+	}
+	isTrailingPosDefined, err := IsTrailingPosDefined(child, axis)
+	if err != nil {
+		return 0, err
+	}
+	if !isLeadingPosDefined || !isTrailingPosDefined {
+		return math.NaN(), nil
+	}
+	leadingPosition, err := LeadingPosition(child, axis, axisSize)
+	if err != nil {
+		return 0, err
+	}
+	trailingPosition, err := TrailingPosition(child, axis, axisSize)
+	if err != nil {
+		return 0, err
+	}
+	return BoundAxis(child, axis, axisSize-(leadingPosition+trailingPosition), axisSize, widthSize)
+}
+
+func WithMeasureFuncSetMeasuredDimensions(node *Node, availableWidth, availableHeight float64,
+	widthMeasureMode, heightMeasureMode MeasureMode, parentWidth, parentHeight float64) error {
+	if node.measure == nil {
+		return errors.New("Expected node to have custom measure function")
+	}
+
+	paddingAndBorderAxisRow, err := PaddingAndBorderForAxis(node, FlexDirectionRow, availableWidth)
+	if err != nil {
+		return err
+	}
+	paddingAndBorderAxisColumn, err := PaddingAndBorderForAxis(node, FlexDirectionColumn, availableWidth)
+	if err != nil {
+		return err
+	}
+	marginAxisRow, err := MarginForAxis(node, FlexDirectionRow, availableWidth)
+	if err != nil {
+		return err
+	}
+	marginAxisColumn, err := MarginForAxis(node, FlexDirectionColumn, availableWidth)
+	if err != nil {
+		return err
+	}
+
+	innerWidth := availableWidth - marginAxisRow - paddingAndBorderAxisRow
+	innerHeight := availableHeight - marginAxisColumn - paddingAndBorderAxisColumn
+
+	var width, height float64
+	if widthMeasureMode == MeasureModeExactly && heightMeasureMode == MeasureModeExactly {
+		// Don't bother sizing the text if both dimensions are already defined.
+		width, err = BoundAxis(node, FlexDirectionRow, availableWidth-marginAxisRow, parentWidth, parentWidth)
+		if err != nil {
+			return err
+		}
+		height, err = BoundAxis(node, FlexDirectionColumn, availableHeight-marginAxisColumn, parentHeight, parentWidth)
+		if err != nil {
+			return err
+		}
+	} else if innerWidth <= 0.0 || innerHeight <= 0.0 {
+		// Don't bother sizing the text if there's no horizontal or vertical
+		// space.
+		width, err = BoundAxis(node, FlexDirectionRow, 0.0, availableWidth, availableWidth)
+		if err != nil {
+			return err
+		}
+		height, err = BoundAxis(node, FlexDirectionColumn, 0.0, availableHeight, availableWidth)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Measure the text under the current constraints.
+		measuredSize := node.measure(node, innerWidth, widthMeasureMode, innerHeight, heightMeasureMode)
+
+		width = availableWidth - marginAxisRow
+		if widthMeasureMode == MeasureModeUndefined || widthMeasureMode == MeasureModeAtmost {
+			width = measuredSize.width + paddingAndBorderAxisRow
+		}
+		width, err = BoundAxis(node, FlexDirectionRow, width, availableWidth, availableWidth)
+		if err != nil {
+			return err
+		}
+		height = availableHeight - marginAxisColumn
+		if heightMeasureMode == MeasureModeUndefined || heightMeasureMode == MeasureModeAtmost {
+			height = measuredSize.height + paddingAndBorderAxisColumn
+		}
+		height, err = BoundAxis(node, FlexDirectionColumn, height, availableHeight, availableWidth)
+		if err != nil {
+			return err
+		}
+	}
+	node.layout.measuredDimensions[DimensionWidth] = width
+	node.layout.measuredDimensions[DimensionHeight] = height
+	return nil
+}
+
+// EmptyContainerSetMeasuredDimensions uses the available values for nodes
+// with no children if they were provided, or the minimum size as indicated by
+// the padding and border sizes.
+func EmptyContainerSetMeasuredDimensions(node *Node, availableWidth, availableHeight float64,
+	widthMeasureMode, heightMeasureMode MeasureMode, parentWidth, parentHeight float64) error {
+	paddingAndBorderAxisRow, err := PaddingAndBorderForAxis(node, FlexDirectionRow, parentWidth)
+	if err != nil {
+		return err
+	}
+	paddingAndBorderAxisColumn, err := PaddingAndBorderForAxis(node, FlexDirectionColumn, parentWidth)
+	if err != nil {
+		return err
+	}
+	marginAxisRow, err := MarginForAxis(node, FlexDirectionRow, parentWidth)
+	if err != nil {
+		return err
+	}
+	marginAxisColumn, err := MarginForAxis(node, FlexDirectionColumn, parentWidth)
+	if err != nil {
+		return err
+	}
+
+	width := availableWidth - marginAxisRow
+	if widthMeasureMode == MeasureModeUndefined || widthMeasureMode == MeasureModeAtmost {
+		width = paddingAndBorderAxisRow
+	}
+	height := availableHeight - marginAxisColumn
+	if heightMeasureMode == MeasureModeUndefined || heightMeasureMode == MeasureModeAtmost {
+		height = paddingAndBorderAxisColumn
+	}
+
+	if node.layout.measuredDimensions[DimensionWidth], err = BoundAxis(node, FlexDirectionRow, width, parentWidth, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.measuredDimensions[DimensionHeight], err = BoundAxis(node, FlexDirectionColumn, height, parentHeight, parentWidth); err != nil {
+		return err
+	}
+	return nil
+}
+
+func FixedSizeSetMeasuredDimensions(node *Node, availableWidth, availableHeight float64,
+	widthMeasureMode, heightMeasureMode MeasureMode, parentWidth, parentHeight float64) (bool, error) {
+	if (widthMeasureMode == MeasureModeAtmost && availableWidth <= 0.0) ||
+		(heightMeasureMode == MeasureModeAtmost && availableHeight <= 0.0) ||
+		(widthMeasureMode == MeasureModeExactly && heightMeasureMode == MeasureModeExactly) {
+		marginAxisColumn, err := MarginForAxis(node, FlexDirectionColumn, parentWidth)
+		if err != nil {
+			return false, err
+		}
+		marginAxisRow, err := MarginForAxis(node, FlexDirectionRow, parentWidth)
+		if err != nil {
+			return false, err
+		}
+
+		width := availableWidth - marginAxisRow
+		if math.IsNaN(availableWidth) || (widthMeasureMode == MeasureModeAtmost && availableWidth < 0.0) {
+			width = 0.0
+		}
+		height := availableHeight - marginAxisColumn
+		if math.IsNaN(availableHeight) || (heightMeasureMode == MeasureModeAtmost && availableHeight < 0.0) {
+			height = 0.0
+		}
+
+		if node.layout.measuredDimensions[DimensionWidth], err = BoundAxis(node, FlexDirectionRow, width, parentWidth, parentWidth); err != nil {
+			return false, err
+		}
+		if node.layout.measuredDimensions[DimensionHeight], err = BoundAxis(node, FlexDirectionColumn, height, parentHeight, parentWidth); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// zeroOutLayout clears the computed position and size of node and
+// invalidates its cached results so that it is laid out again once shown.
+func zeroOutLayout(node *Node) {
+	layout := &node.layout
+	layout.position = [4]float64{}
+	layout.dimensions = [2]float64{}
+	layout.measuredDimensions = [2]float64{}
+	layout.margin = [6]float64{}
+	layout.padding = [6]float64{}
+	layout.border = [6]float64{}
+	layout.contentDimensions = [2]float64{}
+	layout.hadOverflow = false
+	layout.nextCachedMeasurementsIndex = 0
+	layout.cachedLayout.widthMeasureMode = MeasureMode(-1)
+	layout.cachedLayout.heightMeasureMode = MeasureMode(-1)
+	layout.cachedLayout.computedWidth = -1
+	layout.cachedLayout.computedHeight = -1
+	node.hasNewLayout = true
+	node.isDirty = false
+}
+
+func zeroOutLayoutRecursively(node *Node) {
+	zeroOutLayout(node)
+	for _, child := range node.children {
+		zeroOutLayoutRecursively(child)
+	}
+}
+
+// zeroOutSkippedChildren zeroes out the layout of the descendants of node
+// that are hidden with DisplayNone and of the DisplayContents boxes whose
+// children node lays out in their place.
+func zeroOutSkippedChildren(node *Node) {
+	for _, child := range node.children {
+		switch child.style.display {
+		case DisplayNone:
+			zeroOutLayoutRecursively(child)
+		case DisplayContents:
+			zeroOutLayout(child)
+			zeroOutSkippedChildren(child)
+		}
+	}
+}
+
+// NodeLayoutImpl is the main routine that implements a subset of the flexbox
+// layout algorithm described in the W3C CSS documentation:
+// https://www.w3.org/TR/css3-flexbox/.
+//
+// availableWidth and availableHeight are the space available for the node
+// including its margin, and the measure modes describe how they constrain
+// it: MeasureModeUndefined means the size is unconstrained,
+// MeasureModeExactly means it must be exactly that size and
+// MeasureModeAtmost means it may be at most that size. When performLayout is
+// false only the measured dimensions of the node are computed; otherwise the
+// positions and dimensions of all its descendants are set as well.
+func NodeLayoutImpl(ctx *layoutContext, node *Node, availableWidth, availableHeight float64, parentDirection Direction,
+	widthMeasureMode, heightMeasureMode MeasureMode, parentWidth, parentHeight float64, performLayout bool) error {
+	// Set the resolved direction in the node's layout.
+	direction := NodeResolveDirection(node, parentDirection)
+	node.layout.direction = direction
+
+	flexRowDirection := FlexDirectionResolve(FlexDirectionRow, direction)
+	flexColumnDirection := FlexDirectionResolve(FlexDirectionColumn, direction)
+	var err error
+
+	if node.layout.margin[EdgeStart], err = LeadingMargin(node, flexRowDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.margin[EdgeEnd], err = TrailingMargin(node, flexRowDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.margin[EdgeTop], err = LeadingMargin(node, flexColumnDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.margin[EdgeBottom], err = TrailingMargin(node, flexColumnDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.padding[EdgeStart], err = LeadingPadding(node, flexRowDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.padding[EdgeEnd], err = TrailingPadding(node, flexRowDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.padding[EdgeTop], err = LeadingPadding(node, flexColumnDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.padding[EdgeBottom], err = TrailingPadding(node, flexColumnDirection, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.border[EdgeStart], err = LeadingBorder(node, flexRowDirection); err != nil {
+		return err
+	}
+	if node.layout.border[EdgeEnd], err = TrailingBorder(node, flexRowDirection); err != nil {
+		return err
+	}
+	if node.layout.border[EdgeTop], err = LeadingBorder(node, flexColumnDirection); err != nil {
+		return err
+	}
+	if node.layout.border[EdgeBottom], err = TrailingBorder(node, flexColumnDirection); err != nil {
+		return err
+	}
+
+	if node.measure != nil {
+		return WithMeasureFuncSetMeasuredDimensions(node, availableWidth, availableHeight, widthMeasureMode, heightMeasureMode,
+			parentWidth, parentHeight)
+	}
+
+	// Nodes that are not laid out take no space, so clear whatever layout
+	// they were given before.
+	zeroOutSkippedChildren(node)
+
+	children := LayoutChildren(node)
+	childCount := len(children)
+	if childCount == 0 {
+		return EmptyContainerSetMeasuredDimensions(node, availableWidth, availableHeight, widthMeasureMode, heightMeasureMode,
+			parentWidth, parentHeight)
+	}
+
+	// If we're not being asked to perform a full layout we can skip the
+	// algorithm if we already know the size.
+	if !performLayout {
+		fixed, err := FixedSizeSetMeasuredDimensions(node, availableWidth, availableHeight, widthMeasureMode, heightMeasureMode,
+			parentWidth, parentHeight)
+		if err != nil || fixed {
+			return err
+		}
+	}
+
+	// STEP 1: CALCULATE VALUES FOR REMAINDER OF ALGORITHM
+	mainAxis := nodeMainAxis(node, direction)
+	crossAxis := nodeCrossAxis(node, direction)
+	isMainAxisRow := FlexDirectionIsRow(mainAxis)
+	justifyContent := node.style.justifyContent
+	isNodeFlexWrap := node.style.flexWrap != WrapNoWrap
+	isNodeBaselineLayout := IsBaselineLayout(node)
+
+	mainAxisParentSize := parentHeight
+	crossAxisParentSize := parentWidth
+	if isMainAxisRow {
+		mainAxisParentSize = parentWidth
+		crossAxisParentSize = parentHeight
+	}
+
+	var firstAbsoluteChild, currentAbsoluteChild *Node
+
+	leadingPaddingAndBorderMain, err := LeadingPaddingAndBorder(node, mainAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	trailingPaddingAndBorderMain, err := TrailingPaddingAndBorder(node, mainAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	leadingPaddingAndBorderCross, err := LeadingPaddingAndBorder(node, crossAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	paddingAndBorderAxisMain, err := PaddingAndBorderForAxis(node, mainAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+	paddingAndBorderAxisCross, err := PaddingAndBorderForAxis(node, crossAxis, parentWidth)
+	if err != nil {
+		return err
+	}
+
+	measureModeMainDim := heightMeasureMode
+	measureModeCrossDim := widthMeasureMode
+	paddingAndBorderAxisRow := paddingAndBorderAxisCross
+	paddingAndBorderAxisColumn := paddingAndBorderAxisMain
+	if isMainAxisRow {
+		measureModeMainDim = widthMeasureMode
+		measureModeCrossDim = heightMeasureMode
+		paddingAndBorderAxisRow = paddingAndBorderAxisMain
+		paddingAndBorderAxisColumn = paddingAndBorderAxisCross
+	}
+
+	marginAxisRow, err := MarginForAxis(node, FlexDirectionRow, parentWidth)
+	if err != nil {
+		return err
+	}
+	marginAxisColumn, err := MarginForAxis(node, FlexDirectionColumn, parentWidth)
+	if err != nil {
+		return err
+	}
+
+	// STEP 2: DETERMINE AVAILABLE SIZE IN MAIN AND CROSS DIRECTIONS
+	// The min and max dimensions bound the border box; percentages resolve
+	// against the content box of the parent.
+	minInnerWidth := ValueResolve(&node.style.minDimensions[DimensionWidth], parentWidth) - paddingAndBorderAxisRow
+	maxInnerWidth := ValueResolve(&node.style.maxDimensions[DimensionWidth], parentWidth) - paddingAndBorderAxisRow
+	minInnerHeight := ValueResolve(&node.style.minDimensions[DimensionHeight], parentHeight) - paddingAndBorderAxisColumn
+	maxInnerHeight := ValueResolve(&node.style.maxDimensions[DimensionHeight], parentHeight) - paddingAndBorderAxisColumn
+	minInnerMainDim := minInnerHeight
+	maxInnerMainDim := maxInnerHeight
+	if isMainAxisRow {
+		minInnerMainDim = minInnerWidth
+		maxInnerMainDim = maxInnerWidth
+	}
+
+	// Max dimensions override the available size and min dimensions in turn
+	// override both.
+	availableInnerWidth := availableWidth - marginAxisRow - paddingAndBorderAxisRow
+	if !math.IsNaN(availableInnerWidth) {
+		availableInnerWidth = FloatMax(FloatMin(availableInnerWidth, maxInnerWidth), minInnerWidth)
+	}
+	availableInnerHeight := availableHeight - marginAxisColumn - paddingAndBorderAxisColumn
+	if !math.IsNaN(availableInnerHeight) {
+		availableInnerHeight = FloatMax(FloatMin(availableInnerHeight, maxInnerHeight), minInnerHeight)
+	}
+	availableInnerMainDim := availableInnerHeight
+	availableInnerCrossDim := availableInnerWidth
+	if isMainAxisRow {
+		availableInnerMainDim = availableInnerWidth
+		availableInnerCrossDim = availableInnerHeight
+	}
+
+	// Gaps separate items along the main axis and lines along the cross axis.
+	mainAxisGap := GapForAxis(node, mainAxis, availableInnerMainDim)
+	crossAxisGap := GapForAxis(node, crossAxis, availableInnerCrossDim)
+
+	// STEP 3: DETERMINE FLEX BASIS FOR EACH ITEM
+	for i := 0; i < childCount; i++ {
+		child := children[i]
+
+		// Resolve the direction of the child up front as its Start and End
+		// edges are looked up before it is laid out.
+		child.layout.direction = NodeResolveDirection(child, direction)
+
+		if performLayout {
+			// Set the initial position (relative to the parent).
+			if err := NodeSetPosition(child, direction, availableInnerMainDim, availableInnerCrossDim, availableInnerWidth); err != nil {
 				return err
 			}
-			PrintNumberIfNotZero("paddingTop", val)
-			val, err = ComputedEdgeValue(node.style.padding, EdgeBottom, &Value{value: 0, unit: UnitPixel})
-			if err != nil {
+		}
+
+		// Absolute-positioned children don't participate in flex layout. Add
+		// them to a list that we can process later.
+		if child.style.positionType == PositionTypeAbsolute {
+			// Store a private linked list of absolutely positioned children so
+			// that we can efficiently traverse them later.
+			if firstAbsoluteChild == nil {
+				firstAbsoluteChild = child
+			}
+			if currentAbsoluteChild != nil {
+				currentAbsoluteChild.nextChild = child
+			}
+			currentAbsoluteChild = child
+			child.nextChild = nil
+		} else {
+			if err := ComputeFlexBasisForChild(ctx, node, child, availableInnerWidth, widthMeasureMode, availableInnerHeight,
+				availableInnerWidth, availableInnerHeight, heightMeasureMode, direction); err != nil {
 				return err
 			}
-			PrintNumberIfNotZero("paddingBottom", val)
-			val, err = ComputedEdgeValue(node.style.padding, EdgeStart, &Value{value: 0, unit: UnitPixel})
+		}
+	}
+
+	// STEP 4: COLLECT FLEX ITEMS INTO FLEX LINES
+
+	// Indexes of children that represent the first and last items in the
+	// line.
+	startOfLineIndex := 0
+	endOfLineIndex := 0
+
+	// Number of lines.
+	lineCount := 0
+
+	// Accumulated cross dimensions of all lines so far.
+	totalLineCrossDim := 0.0
+
+	// Max main dimension of all the lines.
+	maxLineMainDim := 0.0
+
+	for ; endOfLineIndex < childCount; lineCount, startOfLineIndex = lineCount+1, endOfLineIndex {
+		// Number of items on the currently line. May be different than the
+		// difference between start and end indicates because we skip over
+		// absolute-positioned items.
+		itemsOnLine := 0
+
+		// sizeConsumedOnCurrentLine is accumulation of the dimensions and
+		// margin of all the children on the current line. This will be used
+		// in order to either set the dimensions of the node if none already
+		// exist or to compute the remaining space left for the flexible
+		// children.
+		sizeConsumedOnCurrentLine := 0.0
+
+		// Auto margins on the main axis absorb the free space of the line
+		// instead of justifyContent.
+		numberOfAutoMarginsOnCurrentLine := 0
+
+		// Maintain a linked list of the child nodes that can shrink and/or
+		// grow.
+		var firstRelativeChild, currentRelativeChild *Node
+
+		// Add items to the current line until it's full or we run out of
+		// items.
+		for i := startOfLineIndex; i < childCount; i, endOfLineIndex = i+1, endOfLineIndex+1 {
+			child := children[i]
+			child.lineIndex = uint32(lineCount)
+
+			if child.style.positionType != PositionTypeAbsolute {
+				marginMain, err := MarginForAxis(child, mainAxis, availableInnerWidth)
+				if err != nil {
+					return err
+				}
+				// Lines are filled with the flex basis clamped to the min and
+				// max dimensions of the item, its hypothetical main size.
+				outerFlexBasis := BoundAxisWithinMinAndMax(child, mainAxis, child.layout.computedFlexBasis, availableInnerMainDim) + marginMain
+				if itemsOnLine > 0 {
+					outerFlexBasis += mainAxisGap
+				}
+
+				// If this is a multi-line flow and this item pushes us over the
+				// available size, we've hit the end of the current line. Break
+				// out of the loop and lay out the current line.
+				if sizeConsumedOnCurrentLine+outerFlexBasis > availableInnerMainDim && isNodeFlexWrap && itemsOnLine > 0 {
+					break
+				}
+
+				sizeConsumedOnCurrentLine += outerFlexBasis
+				itemsOnLine++
+
+				leadingAuto, trailingAuto, err := AutoMargins(child, mainAxis)
+				if err != nil {
+					return err
+				}
+				if leadingAuto {
+					numberOfAutoMarginsOnCurrentLine++
+				}
+				if trailingAuto {
+					numberOfAutoMarginsOnCurrentLine++
+				}
+
+				// Store a private linked list of children that need to be laid
+				// out.
+				if firstRelativeChild == nil {
+					firstRelativeChild = child
+				}
+				if currentRelativeChild != nil {
+					currentRelativeChild.nextChild = child
+				}
+				currentRelativeChild = child
+				child.nextChild = nil
+			}
+		}
+
+		// If we don't need to measure the cross axis, we can skip the entire
+		// flex step.
+		canSkipFlex := !performLayout && measureModeCrossDim == MeasureModeExactly
+
+		// In order to position the elements in the main axis, we have two
+		// controls. The space between the beginning and the first element and
+		// the space between each two elements.
+		leadingMainDim := 0.0
+		betweenMainDim := 0.0
+
+		// STEP 5: RESOLVING FLEXIBLE LENGTHS ON MAIN AXIS
+		// If the main size of the node is not exact it still must not violate
+		// its min and max dimensions.
+		if measureModeMainDim != MeasureModeExactly {
+			if !math.IsNaN(minInnerMainDim) && sizeConsumedOnCurrentLine < minInnerMainDim {
+				availableInnerMainDim = minInnerMainDim
+			} else if !math.IsNaN(maxInnerMainDim) && sizeConsumedOnCurrentLine > maxInnerMainDim {
+				availableInnerMainDim = maxInnerMainDim
+			}
+		}
+
+		// Calculate the remaining available space that needs to be allocated.
+		// If the main dimension size isn't known, it is computed based on the
+		// line length, so there's no more space left to distribute.
+		remainingFreeSpace := 0.0
+		if !math.IsNaN(availableInnerMainDim) {
+			remainingFreeSpace = availableInnerMainDim - sizeConsumedOnCurrentLine
+		} else if sizeConsumedOnCurrentLine < 0 {
+			// availableInnerMainDim is indefinite which means the node is being
+			// sized based on its content. sizeConsumedOnCurrentLine is negative
+			// which means the node will allocate 0 points for its content.
+			// Consequently, remainingFreeSpace is 0 - sizeConsumedOnCurrentLine.
+			remainingFreeSpace = -sizeConsumedOnCurrentLine
+		}
+
+		originalRemainingFreeSpace := remainingFreeSpace
+		deltaFreeSpace := 0.0
+
+		if !canSkipFlex {
+			mainSizes, err := ResolveFlexibleLengths(firstRelativeChild, mainAxis, remainingFreeSpace,
+				availableInnerMainDim, availableInnerWidth)
 			if err != nil {
 				return err
 			}
-			PrintNumberIfNotZero("paddingStart", val)
-			val, err = ComputedEdgeValue(node.style.padding, EdgeEnd, &Value{value: 0, unit: UnitPixel})
+
+			i := 0
+			for currentRelativeChild = firstRelativeChild; currentRelativeChild != nil; currentRelativeChild, i = currentRelativeChild.nextChild, i+1 {
+				childFlexBasis := BoundAxisWithinMinAndMax(currentRelativeChild, mainAxis, currentRelativeChild.layout.computedFlexBasis,
+					availableInnerMainDim)
+				updatedMainSize := mainSizes[i]
+				deltaFreeSpace -= updatedMainSize - childFlexBasis
+
+				marginRow, err := MarginForAxis(currentRelativeChild, FlexDirectionRow, availableInnerWidth)
+				if err != nil {
+					return err
+				}
+				marginColumn, err := MarginForAxis(currentRelativeChild, FlexDirectionColumn, availableInnerWidth)
+				if err != nil {
+					return err
+				}
+
+				isStretched, err := IsStretched(node, currentRelativeChild, crossAxis)
+				if err != nil {
+					return err
+				}
+
+				var childWidth, childHeight float64
+				var childWidthMeasureMode, childHeightMeasureMode MeasureMode
+
+				// Stretched items of a wrapping container take the cross size
+				// of their line rather than the one of the container. That
+				// size is only known once all the lines are collected, so
+				// they are stretched during cross-axis alignment instead.
+				if isMainAxisRow {
+					childWidth = updatedMainSize + marginRow
+					childWidthMeasureMode = MeasureModeExactly
+
+					if !math.IsNaN(availableInnerCrossDim) &&
+						!IsStyleDimDefined(currentRelativeChild, FlexDirectionColumn, availableInnerHeight) &&
+						heightMeasureMode == MeasureModeExactly && !isNodeFlexWrap && isStretched {
+						childHeight = availableInnerCrossDim
+						childHeightMeasureMode = MeasureModeExactly
+					} else if !IsStyleDimDefined(currentRelativeChild, FlexDirectionColumn, availableInnerHeight) {
+						childHeight = availableInnerCrossDim
+						childHeightMeasureMode = MeasureModeAtmost
+						if math.IsNaN(childHeight) {
+							childHeightMeasureMode = MeasureModeUndefined
+						}
+					} else {
+						childHeight = ValueResolve(&currentRelativeChild.style.dimensions[DimensionHeight], availableInnerHeight) + marginColumn
+						childHeightMeasureMode = MeasureModeExactly
+					}
+				} else {
+					childHeight = updatedMainSize + marginColumn
+					childHeightMeasureMode = MeasureModeExactly
+
+					if !math.IsNaN(availableInnerCrossDim) &&
+						!IsStyleDimDefined(currentRelativeChild, FlexDirectionRow, availableInnerWidth) &&
+						widthMeasureMode == MeasureModeExactly && !isNodeFlexWrap && isStretched {
+						childWidth = availableInnerCrossDim
+						childWidthMeasureMode = MeasureModeExactly
+					} else if !IsStyleDimDefined(currentRelativeChild, FlexDirectionRow, availableInnerWidth) {
+						childWidth = availableInnerCrossDim
+						childWidthMeasureMode = MeasureModeAtmost
+						if math.IsNaN(childWidth) {
+							childWidthMeasureMode = MeasureModeUndefined
+						}
+					} else {
+						childWidth = ValueResolve(&currentRelativeChild.style.dimensions[DimensionWidth], availableInnerWidth) + marginRow
+						childWidthMeasureMode = MeasureModeExactly
+					}
+				}
+
+				// The aspect ratio derives the cross size from the flexed main
+				// size, but the size of the container takes priority over the
+				// flexed size.
+				if HasAspectRatio(currentRelativeChild) {
+					if isMainAxisRow {
+						childHeight, err = AspectRatioSize(currentRelativeChild, FlexDirectionRow, childWidth-marginRow,
+							availableInnerHeight, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						if IsFlex(currentRelativeChild) && childHeight > availableInnerHeight {
+							childHeight = availableInnerHeight
+							if childWidth, err = AspectRatioSize(currentRelativeChild, FlexDirectionColumn, childHeight,
+								availableInnerWidth, availableInnerWidth); err != nil {
+								return err
+							}
+							childWidth += marginRow
+						}
+						childHeight += marginColumn
+						childHeightMeasureMode = MeasureModeExactly
+					} else {
+						childWidth, err = AspectRatioSize(currentRelativeChild, FlexDirectionColumn, childHeight-marginColumn,
+							availableInnerWidth, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						if IsFlex(currentRelativeChild) && childWidth > availableInnerWidth {
+							childWidth = availableInnerWidth
+							if childHeight, err = AspectRatioSize(currentRelativeChild, FlexDirectionRow, childWidth,
+								availableInnerHeight, availableInnerWidth); err != nil {
+								return err
+							}
+							childHeight += marginColumn
+						}
+						childWidth += marginRow
+						childWidthMeasureMode = MeasureModeExactly
+					}
+				}
+
+				if childWidthMeasureMode, childWidth, err = ConstrainMaxSizeForMode(currentRelativeChild, FlexDirectionRow,
+					availableInnerWidth, availableInnerWidth, childWidthMeasureMode, childWidth); err != nil {
+					return err
+				}
+				if childHeightMeasureMode, childHeight, err = ConstrainMaxSizeForMode(currentRelativeChild, FlexDirectionColumn,
+					availableInnerHeight, availableInnerWidth, childHeightMeasureMode, childHeight); err != nil {
+					return err
+				}
+
+				requiresStretchLayout := !IsStyleDimDefined(currentRelativeChild, crossAxis, availableInnerCrossDim) && isStretched &&
+					!HasAspectRatio(currentRelativeChild)
+
+				// The baseline of a child is found from the positions of its
+				// descendants, so baseline aligned children are laid out even
+				// when the node is only measured.
+				requiresBaselineLayout := isNodeBaselineLayout && AlignItem(node, currentRelativeChild) == AlignBaseLine
+
+				// Recursively call the layout algorithm for this child with the
+				// updated main size.
+				if _, err := LayoutNodeInternal(ctx, currentRelativeChild, childWidth, childHeight, direction,
+					childWidthMeasureMode, childHeightMeasureMode, availableInnerWidth, availableInnerHeight,
+					(performLayout || requiresBaselineLayout) && !requiresStretchLayout); err != nil {
+					return err
+				}
+			}
+		}
+
+		remainingFreeSpace = originalRemainingFreeSpace + deltaFreeSpace
+
+		// STEP 6: MAIN-AXIS JUSTIFICATION & CROSS-AXIS SIZE DETERMINATION
+
+		// At this point, all the children have their dimensions set in the
+		// main axis. Their dimensions are also set in the cross axis with the
+		// exception of items that are aligned "stretch". We need to compute
+		// these stretch values and set the final positions.
+
+		// If we are using "at most" rules in the main axis there is no space
+		// left to distribute.
+		if measureModeMainDim == MeasureModeAtmost && remainingFreeSpace > 0 {
+			remainingFreeSpace = 0
+		}
+
+		autoMarginMainDim := 0.0
+		if numberOfAutoMarginsOnCurrentLine > 0 {
+			autoMarginMainDim = FloatMax(remainingFreeSpace, 0) / float64(numberOfAutoMarginsOnCurrentLine)
+		} else {
+			switch justifyContent {
+			case JustifyCenter:
+				leadingMainDim = remainingFreeSpace / 2
+			case JustifyFlexEnd:
+				leadingMainDim = remainingFreeSpace
+			case JustifySpaceBetween:
+				if itemsOnLine > 1 {
+					betweenMainDim = FloatMax(remainingFreeSpace, 0) / float64(itemsOnLine-1)
+				} else {
+					betweenMainDim = 0
+				}
+			case JustifySpaceAround:
+				// Space on the edges is half of the space between elements.
+				betweenMainDim = remainingFreeSpace / float64(itemsOnLine)
+				leadingMainDim = betweenMainDim / 2
+			case JustifySpaceEvenly:
+				// Space on the edges is the same as the space between elements.
+				betweenMainDim = remainingFreeSpace / float64(itemsOnLine+1)
+				leadingMainDim = betweenMainDim
+			}
+		}
+
+		mainDim := leadingPaddingAndBorderMain + leadingMainDim
+		crossDim := 0.0
+		maxAscentForCurrentLine := 0.0
+		maxDescentForCurrentLine := 0.0
+		itemsPlacedOnLine := 0
+
+		for i := startOfLineIndex; i < endOfLineIndex; i++ {
+			child := children[i]
+
+			// Absolute elements do not take part in this phase, they are
+			// positioned once the size of the container is known.
+			if child.style.positionType != PositionTypeAbsolute {
+				if itemsPlacedOnLine > 0 {
+					mainDim += mainAxisGap
+				}
+				itemsPlacedOnLine++
+
+				leadingAuto, trailingAuto, err := AutoMargins(child, mainAxis)
+				if err != nil {
+					return err
+				}
+				if leadingAuto {
+					mainDim += autoMarginMainDim
+				}
+
+				// Now that we placed the element, we need to update the
+				// variables.
+				if performLayout {
+					child.layout.position[pos[mainAxis]] += mainDim
+				}
+
+				if trailingAuto {
+					mainDim += autoMarginMainDim
+				}
+
+				if canSkipFlex {
+					// If we skipped the flex step, then we can't rely on the
+					// measuredDimensions because they weren't computed. This
+					// means we can't call DimWithMargin.
+					marginMain, err := MarginForAxis(child, mainAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					mainDim += betweenMainDim + marginMain +
+						BoundAxisWithinMinAndMax(child, mainAxis, child.layout.computedFlexBasis, availableInnerMainDim)
+					crossDim = availableInnerCrossDim
+				} else {
+					// The main dimension is the sum of all the elements
+					// dimension plus the spacing.
+					dimWithMarginMain, err := DimWithMargin(child, mainAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					mainDim += betweenMainDim + dimWithMarginMain
+
+					if isNodeBaselineLayout && AlignItem(node, child) == AlignBaseLine {
+						// If the child is baseline aligned then the cross
+						// dimension is calculated by adding maxAscent and
+						// maxDescent from the baseline.
+						ascent, descent, err := baselineAscentAndDescent(child, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						maxAscentForCurrentLine = FloatMax(maxAscentForCurrentLine, ascent)
+						maxDescentForCurrentLine = FloatMax(maxDescentForCurrentLine, descent)
+					} else {
+						// The cross dimension is the max of the elements
+						// dimension since there can only be one element in that
+						// cross dimension.
+						dimWithMarginCross, err := DimWithMargin(child, crossAxis, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						crossDim = FloatMax(crossDim, dimWithMarginCross)
+					}
+				}
+			}
+		}
+
+		mainDim += trailingPaddingAndBorderMain
+		crossDim = FloatMax(crossDim, maxAscentForCurrentLine+maxDescentForCurrentLine)
+
+		containerCrossAxis := availableInnerCrossDim
+		if measureModeCrossDim == MeasureModeUndefined || measureModeCrossDim == MeasureModeAtmost {
+			// Compute the cross axis from the max cross dimension of the
+			// children.
+			containerCrossAxis, err = BoundAxis(node, crossAxis, crossDim+paddingAndBorderAxisCross, crossAxisParentSize, parentWidth)
 			if err != nil {
 				return err
 			}
-			PrintNumberIfNotZero("paddingEnd", val)
-		}
-		PrintNumberIfNotUndefined("width", &node.style.dimensions[DimensionWidth])
-		PrintNumberIfNotUndefined("height", &node.style.dimensions[DimensionHeight])
-		PrintNumberIfNotUndefined("maxWidth", &node.style.maxDimensions[DimensionWidth])
-		PrintNumberIfNotUndefined("maxHeigth", &node.style.maxDimensions[DimensionHeight])
-		PrintNumberIfNotUndefined("minWidth", &node.style.minDimensions[DimensionWidth])
-		PrintNumberIfNotUndefined("minHeight", &node.style.minDimensions[DimensionHeight])
-		log.Printf("position: '%s', ", node.style.positionType)
-		val, err := ComputedEdgeValue(node.style.position, EdgeLeft, &Value{value: math.NaN(), unit: UnitUndefined})
+			containerCrossAxis -= paddingAndBorderAxisCross
+
+			if measureModeCrossDim == MeasureModeAtmost {
+				containerCrossAxis = FloatMin(containerCrossAxis, availableInnerCrossDim)
+			}
+		}
+
+		// If there's no flex wrap, the cross dimension is defined by the
+		// container.
+		if !isNodeFlexWrap && measureModeCrossDim == MeasureModeExactly {
+			crossDim = availableInnerCrossDim
+		}
+
+		// Clamp to the min/max size specified on the container.
+		crossDim, err = BoundAxis(node, crossAxis, crossDim+paddingAndBorderAxisCross, crossAxisParentSize, parentWidth)
+		if err != nil {
+			return err
+		}
+		crossDim -= paddingAndBorderAxisCross
+
+		if lineCount > 0 {
+			totalLineCrossDim += crossAxisGap
+		}
+
+		// STEP 7: CROSS-AXIS ALIGNMENT
+		// We can skip child alignment if we're just measuring the container.
+		if performLayout {
+			for i := startOfLineIndex; i < endOfLineIndex; i++ {
+				child := children[i]
+
+				if child.style.positionType != PositionTypeAbsolute {
+					leadingCrossDim := leadingPaddingAndBorderCross
+
+					// For a relative children, we're either using alignItems
+					// (parent) or alignSelf (child) in order to determine the
+					// position in the cross axis.
+					alignItem := AlignItem(node, child)
+					leadingAuto, trailingAuto, err := AutoMargins(child, crossAxis)
+					if err != nil {
+						return err
+					}
+
+					// If the child uses align stretch, we need to lay it out one
+					// more time, this time forcing the cross-axis size to be the
+					// computed cross size for the current line.
+					if alignItem == AlignStretch && !leadingAuto && !trailingAuto {
+						// The cross size of a child with an aspect ratio follows
+						// from its main size instead.
+						if !IsStyleDimDefined(child, crossAxis, availableInnerCrossDim) && !HasAspectRatio(child) {
+							childWidth := crossDim
+							childHeight := crossDim
+							if isMainAxisRow {
+								marginMain, err := MarginForAxis(child, mainAxis, availableInnerWidth)
+								if err != nil {
+									return err
+								}
+								childWidth = child.layout.measuredDimensions[DimensionWidth] + marginMain
+							} else {
+								marginMain, err := MarginForAxis(child, mainAxis, availableInnerWidth)
+								if err != nil {
+									return err
+								}
+								childHeight = child.layout.measuredDimensions[DimensionHeight] + marginMain
+							}
+
+							if _, err := LayoutNodeInternal(ctx, child, childWidth, childHeight, direction,
+								MeasureModeExactly, MeasureModeExactly, availableInnerWidth, availableInnerHeight, true); err != nil {
+								return err
+							}
+						}
+					} else {
+						dimWithMarginCross, err := DimWithMargin(child, crossAxis, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						remainingCrossDim := containerCrossAxis - dimWithMarginCross
+
+						// Auto margins on the cross axis override alignItems and
+						// alignSelf.
+						switch {
+						case leadingAuto && trailingAuto:
+							leadingCrossDim += FloatMax(remainingCrossDim, 0) / 2
+						case trailingAuto:
+						case leadingAuto:
+							leadingCrossDim += FloatMax(remainingCrossDim, 0)
+						case alignItem == AlignCenter:
+							leadingCrossDim += remainingCrossDim / 2
+						case alignItem == AlignFlexEnd:
+							leadingCrossDim += remainingCrossDim
+						}
+					}
+
+					// And we apply the position
+					child.layout.position[pos[crossAxis]] += totalLineCrossDim + leadingCrossDim
+				}
+			}
+		}
+
+		totalLineCrossDim += crossDim
+		maxLineMainDim = FloatMax(maxLineMainDim, mainDim)
+	}
+
+	// STEP 8: MULTI-LINE CONTENT ALIGNMENT
+	if performLayout && (isNodeFlexWrap || isNodeBaselineLayout) {
+		// crossDimLead is added to the cross size of every line while
+		// betweenCrossDim only separates consecutive lines.
+		crossDimLead := 0.0
+		betweenCrossDim := 0.0
+		currentLead := leadingPaddingAndBorderCross
+
+		if isNodeFlexWrap && !math.IsNaN(availableInnerCrossDim) {
+			remainingAlignContentDim := availableInnerCrossDim - totalLineCrossDim
+
+			switch node.style.alignContent {
+			case AlignFlexEnd:
+				currentLead += remainingAlignContentDim
+			case AlignCenter:
+				currentLead += remainingAlignContentDim / 2
+			case AlignStretch:
+				if remainingAlignContentDim > 0 {
+					crossDimLead = remainingAlignContentDim / float64(lineCount)
+				}
+			case AlignSpaceBetween:
+				if remainingAlignContentDim > 0 && lineCount > 1 {
+					betweenCrossDim = remainingAlignContentDim / float64(lineCount-1)
+				}
+			case AlignSpaceAround:
+				if remainingAlignContentDim > 0 {
+					// Space on the edges is half of the space between lines.
+					betweenCrossDim = remainingAlignContentDim / float64(lineCount)
+					currentLead += betweenCrossDim / 2
+				} else {
+					currentLead += remainingAlignContentDim / 2
+				}
+			case AlignSpaceEvenly:
+				if remainingAlignContentDim > 0 {
+					// Space on the edges is the same as the space between
+					// lines.
+					betweenCrossDim = remainingAlignContentDim / float64(lineCount+1)
+					currentLead += betweenCrossDim
+				} else {
+					currentLead += remainingAlignContentDim / 2
+				}
+			}
+		}
+
+		endIndex := 0
+		for i := 0; i < lineCount; i++ {
+			startIndex := endIndex
+			ii := startIndex
+
+			// compute the line's height and find the endIndex
+			lineHeight := 0.0
+			maxAscentForCurrentLine := 0.0
+			maxDescentForCurrentLine := 0.0
+			for ; ii < childCount; ii++ {
+				child := children[ii]
+
+				if child.style.positionType != PositionTypeAbsolute {
+					if child.lineIndex != uint32(i) {
+						break
+					}
+
+					if IsLayoutDimDefined(child, crossAxis) {
+						marginCross, err := MarginForAxis(child, crossAxis, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						lineHeight = FloatMax(lineHeight, child.layout.measuredDimensions[dim[crossAxis]]+marginCross)
+					}
+					if AlignItem(node, child) == AlignBaseLine {
+						ascent, descent, err := baselineAscentAndDescent(child, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						maxAscentForCurrentLine = FloatMax(maxAscentForCurrentLine, ascent)
+						maxDescentForCurrentLine = FloatMax(maxDescentForCurrentLine, descent)
+						lineHeight = FloatMax(lineHeight, maxAscentForCurrentLine+maxDescentForCurrentLine)
+					}
+				}
+			}
+			endIndex = ii
+			lineHeight += crossDimLead
+
+			for ii = startIndex; ii < endIndex; ii++ {
+				child := children[ii]
+
+				if child.style.positionType == PositionTypeAbsolute {
+					continue
+				}
+				alignItem := AlignItem(node, child)
+				if !isNodeFlexWrap && alignItem != AlignBaseLine {
+					// A single line was already aligned against the container
+					// in the cross-axis alignment step.
+					continue
+				}
+
+				// Auto margins on the cross axis align the child within its
+				// line regardless of alignItems and alignSelf.
+				leadingAuto, trailingAuto, err := AutoMargins(child, crossAxis)
+				if err != nil {
+					return err
+				}
+				if leadingAuto || trailingAuto {
+					leadingMargin, err := LeadingMargin(child, crossAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					dimWithMarginCross, err := DimWithMargin(child, crossAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					autoMarginCrossDim := 0.0
+					if leadingAuto {
+						autoMarginCrossDim = FloatMax(lineHeight-dimWithMarginCross, 0)
+						if trailingAuto {
+							autoMarginCrossDim /= 2
+						}
+					}
+					child.layout.position[pos[crossAxis]] = currentLead + leadingMargin + autoMarginCrossDim
+					continue
+				}
+
+				switch alignItem {
+				case AlignFlexEnd:
+					trailingMargin, err := TrailingMargin(child, crossAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					child.layout.position[pos[crossAxis]] = currentLead + lineHeight - trailingMargin -
+						child.layout.measuredDimensions[dim[crossAxis]]
+				case AlignCenter:
+					childHeight := child.layout.measuredDimensions[dim[crossAxis]]
+					child.layout.position[pos[crossAxis]] = currentLead + (lineHeight-childHeight)/2
+				case AlignBaseLine:
+					baseline, err := Baseline(child)
+					if err != nil {
+						return err
+					}
+					leadingPosition, err := LeadingPosition(child, FlexDirectionColumn, availableInnerCrossDim)
+					if err != nil {
+						return err
+					}
+					child.layout.position[EdgeTop] = currentLead + maxAscentForCurrentLine - baseline + leadingPosition
+				case AlignStretch:
+					leadingMargin, err := LeadingMargin(child, crossAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					child.layout.position[pos[crossAxis]] = currentLead + leadingMargin
+
+					// Remeasure the child with the line height as it has only
+					// been measured with the cross size of the line without
+					// the distributed align-content space yet.
+					if !IsStyleDimDefined(child, crossAxis, availableInnerCrossDim) && !HasAspectRatio(child) {
+						marginMain, err := MarginForAxis(child, mainAxis, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						marginCross, err := MarginForAxis(child, crossAxis, availableInnerWidth)
+						if err != nil {
+							return err
+						}
+						childWidth := lineHeight
+						childHeight := lineHeight
+						if isMainAxisRow {
+							childWidth = child.layout.measuredDimensions[DimensionWidth] + marginMain
+						} else {
+							childHeight = child.layout.measuredDimensions[DimensionHeight] + marginMain
+						}
+
+						if !FloatsEqual(lineHeight, child.layout.measuredDimensions[dim[crossAxis]]+marginCross) {
+							if _, err := LayoutNodeInternal(ctx, child, childWidth, childHeight, direction,
+								MeasureModeExactly, MeasureModeExactly, availableInnerWidth, availableInnerHeight, true); err != nil {
+								return err
+							}
+						}
+					}
+				case AlignAuto:
+				default:
+					leadingMargin, err := LeadingMargin(child, crossAxis, availableInnerWidth)
+					if err != nil {
+						return err
+					}
+					child.layout.position[pos[crossAxis]] = currentLead + leadingMargin
+				}
+			}
+
+			currentLead += lineHeight + betweenCrossDim + crossAxisGap
+		}
+	}
+
+	// STEP 9: COMPUTING FINAL DIMENSIONS
+	if node.layout.measuredDimensions[DimensionWidth], err = BoundAxis(node, FlexDirectionRow, availableWidth-marginAxisRow,
+		parentWidth, parentWidth); err != nil {
+		return err
+	}
+	if node.layout.measuredDimensions[DimensionHeight], err = BoundAxis(node, FlexDirectionColumn, availableHeight-marginAxisColumn,
+		parentHeight, parentWidth); err != nil {
+		return err
+	}
+
+	// If the user didn't specify a width or height for the node, set the
+	// dimensions based on the children.
+	if measureModeMainDim == MeasureModeUndefined {
+		// Clamp the size to the min/max size, if specified, and make sure it
+		// doesn't go below the padding and border amount.
+		if node.layout.measuredDimensions[dim[mainAxis]], err = BoundAxis(node, mainAxis, maxLineMainDim,
+			mainAxisParentSize, parentWidth); err != nil {
+			return err
+		}
+	} else if measureModeMainDim == MeasureModeAtmost {
+		node.layout.measuredDimensions[dim[mainAxis]] = FloatMax(
+			FloatMin(availableInnerMainDim+paddingAndBorderAxisMain,
+				BoundAxisWithinMinAndMax(node, mainAxis, maxLineMainDim, mainAxisParentSize)),
+			paddingAndBorderAxisMain)
+	}
+
+	if measureModeCrossDim == MeasureModeUndefined {
+		// Clamp the size to the min/max size, if specified, and make sure it
+		// doesn't go below the padding and border amount.
+		if node.layout.measuredDimensions[dim[crossAxis]], err = BoundAxis(node, crossAxis,
+			totalLineCrossDim+paddingAndBorderAxisCross, crossAxisParentSize, parentWidth); err != nil {
+			return err
+		}
+	} else if measureModeCrossDim == MeasureModeAtmost {
+		node.layout.measuredDimensions[dim[crossAxis]] = FloatMax(
+			FloatMin(availableInnerCrossDim+paddingAndBorderAxisCross,
+				BoundAxisWithinMinAndMax(node, crossAxis, totalLineCrossDim+paddingAndBorderAxisCross, crossAxisParentSize)),
+			paddingAndBorderAxisCross)
+	}
+
+	// As we only wrapped in normal direction yet, we need to reverse the
+	// positions on wrap-reverse.
+	if performLayout && node.style.flexWrap == WrapWrapReverse {
+		for _, child := range children {
+			if child.style.positionType != PositionTypeAbsolute {
+				child.layout.position[pos[crossAxis]] = node.layout.measuredDimensions[dim[crossAxis]] -
+					child.layout.position[pos[crossAxis]] - child.layout.measuredDimensions[dim[crossAxis]]
+			}
+		}
+	}
+
+	// Static nodes don't form a containing block: their absolute children are
+	// laid out by the nearest non-static ancestor instead. The root always
+	// forms one.
+	formsContainingBlock := node.style.positionType != PositionTypeStatic || node.parent == nil
+
+	// STEP 10: SIZING AND POSITIONING ABSOLUTE CHILDREN
+	if performLayout && formsContainingBlock {
+		for currentAbsoluteChild = firstAbsoluteChild; currentAbsoluteChild != nil; currentAbsoluteChild = currentAbsoluteChild.nextChild {
+			if err := AbsoluteLayoutChild(ctx, node, currentAbsoluteChild, widthMeasureMode, direction); err != nil {
+				return err
+			}
+		}
+	}
+
+	// STEP 11: SETTING TRAILING POSITIONS FOR CHILDREN
+	if performLayout {
+		needsMainTrailingPos := mainAxis == FlexDirectionRowReverse || mainAxis == FlexDirectionColumnReverse
+		needsCrossTrailingPos := crossAxis == FlexDirectionRowReverse || crossAxis == FlexDirectionColumnReverse
+
+		// Set trailing position if necessary.
+		if needsMainTrailingPos || needsCrossTrailingPos {
+			for i := 0; i < childCount; i++ {
+				child := children[i]
+
+				if needsMainTrailingPos {
+					SetChildTrailingPosition(node, child, mainAxis)
+				}
+
+				if needsCrossTrailingPos {
+					SetChildTrailingPosition(node, child, crossAxis)
+				}
+			}
+		}
+	}
+
+	// STEP 12: SIZING AND POSITIONING ABSOLUTE DESCENDANTS OF STATIC CHILDREN
+	if performLayout && formsContainingBlock {
+		for _, child := range children {
+			if child.style.positionType == PositionTypeStatic {
+				if err := layoutAbsoluteDescendants(ctx, node, child, widthMeasureMode, direction,
+					child.layout.position[EdgeLeft], child.layout.position[EdgeTop]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// layoutAbsoluteDescendants lays out the absolute children of the static
+// node current, and those of its static descendants, against containingNode,
+// their nearest non-static ancestor. left and top are the offsets of current
+// within containingNode; they are subtracted so that the positions of the
+// children remain relative to their parent.
+func layoutAbsoluteDescendants(ctx *layoutContext, containingNode, current *Node, widthMode MeasureMode, direction Direction,
+	left, top float64) error {
+	mainAxis := nodeMainAxis(containingNode, direction)
+	crossAxis := nodeCrossAxis(containingNode, direction)
+
+	for _, child := range LayoutChildren(current) {
+		switch child.style.positionType {
+		case PositionTypeAbsolute:
+			if err := AbsoluteLayoutChild(ctx, containingNode, child, widthMode, direction); err != nil {
+				return err
+			}
+			for _, axis := range []FlexDirection{mainAxis, crossAxis} {
+				if axis == FlexDirectionRowReverse || axis == FlexDirectionColumnReverse {
+					SetChildTrailingPosition(containingNode, child, axis)
+				}
+			}
+			child.layout.position[EdgeLeft] -= left
+			child.layout.position[EdgeTop] -= top
+		case PositionTypeStatic:
+			if err := layoutAbsoluteDescendants(ctx, containingNode, child, widthMode, direction,
+				left+child.layout.position[EdgeLeft], top+child.layout.position[EdgeTop]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetContentDimensions records the size of the area spanned by the children
+// of node once they are laid out, margins and the padding of node included,
+// and whether it extends past the padding box of node on either side.
+func SetContentDimensions(node *Node) error {
+	children := LayoutChildren(node)
+	paddingBoxWidth, err := paddingBoxSize(node, FlexDirectionRow)
+	if err != nil {
+		return err
+	}
+	innerWidth := paddingBoxWidth - node.layout.padding[EdgeStart] - node.layout.padding[EdgeEnd]
+
+	node.layout.hadOverflow = false
+	for _, axis := range []FlexDirection{FlexDirectionRow, FlexDirectionColumn} {
+		leadingPadding, err := GetLayoutPadding(node, leading[axis])
 		if err != nil {
 			return err
 		}
-		PrintNumberIfNotUndefined("left", val)
-		val, err = ComputedEdgeValue(node.style.position, EdgeRight, &Value{value: math.NaN(), unit: UnitUndefined})
+		trailingPadding, err := GetLayoutPadding(node, trailing[axis])
 		if err != nil {
 			return err
 		}
-		PrintNumberIfNotUndefined("right", val)
-		val, err = ComputedEdgeValue(node.style.position, EdgeTop, &Value{value: math.NaN(), unit: UnitUndefined})
+		leadingBorder, err := LeadingBorder(node, axis)
 		if err != nil {
 			return err
 		}
-		PrintNumberIfNotUndefined("top", val)
-		val, err = ComputedEdgeValue(node.style.position, EdgeBottom, &Value{value: math.NaN(), unit: UnitUndefined})
+		paddingBox, err := paddingBoxSize(node, axis)
 		if err != nil {
 			return err
 		}
-		PrintNumberIfNotUndefined("bottom", val)
 
-	}
-	childCount := len(node.children)
-	if (options&PrintOptionsChildren != 0) && childCount > 0 {
-		log.Print("children: [\n")
-		for i := 0; i < childCount; i++ {
-			err := NodePrintInternal(GetChild(node, i), options, level+1)
+		// Measure from the leading edge of the padding box; children may
+		// overflow it on either side.
+		contentStart := 0.0
+		contentEnd := paddingBox
+		for _, child := range children {
+			// The absolute children of a static node are positioned by
+			// another node and are not part of its content.
+			if child.style.positionType == PositionTypeAbsolute && node.style.positionType == PositionTypeStatic {
+				continue
+			}
+			leadingMargin, err := LeadingMargin(child, axis, innerWidth)
+			if err != nil {
+				return err
+			}
+			trailingMargin, err := TrailingMargin(child, axis, innerWidth)
 			if err != nil {
 				return err
 			}
+			childStart := child.layout.position[pos[axis]] - leadingMargin - leadingPadding - leadingBorder
+			childEnd := child.layout.position[pos[axis]] + child.layout.measuredDimensions[dim[axis]] + trailingMargin +
+				trailingPadding - leadingBorder
+			contentStart = FloatMin(contentStart, childStart)
+			contentEnd = FloatMax(contentEnd, childEnd)
+		}
+
+		node.layout.contentDimensions[dim[axis]] = contentEnd - contentStart
+		if !FloatsEqual(contentEnd-contentStart, paddingBox) {
+			node.layout.hadOverflow = true
 		}
-		Indent(level)
-		log.Print("]},\n")
-	} else {
-		log.Print("]},\n")
 	}
 	return nil
 }
 
-func NodePrint(node *Node, options PrintOptions) error {
-	return NodePrintInternal(node, options, 0)
+func measureModeSizeIsExactAndMatchesOldMeasuredSize(sizeMode MeasureMode, size, lastComputedSize float64) bool {
+	return sizeMode == MeasureModeExactly && FloatsEqual(size, lastComputedSize)
 }
 
-var leading [4]Edge
+func measureModeOldSizeIsUnspecifiedAndStillFits(sizeMode MeasureMode, size float64, lastSizeMode MeasureMode, lastComputedSize float64) bool {
+	return sizeMode == MeasureModeAtmost && lastSizeMode == MeasureModeUndefined && size >= lastComputedSize
+}
 
-func init() {
-	leading[FlexDirectionColumn] = EdgeTop
-	leading[FlexDirectionColumnReverse] = EdgeBottom
-	leading[FlexDirectionRow] = EdgeLeft
-	leading[FlexDirectionRowReverse] = EdgeRight
+func measureModeNewMeasureSizeIsStricterAndStillValid(sizeMode MeasureMode, size float64, lastSizeMode MeasureMode,
+	lastSize, lastComputedSize float64) bool {
+	return lastSizeMode == MeasureModeAtmost && sizeMode == MeasureModeAtmost && lastSize > size && lastComputedSize <= size
 }
 
-var trailing [4]Edge
+// CanUseCachedMeasurement reports whether a measurement computed under the
+// last constraints is still valid under the new ones. A cached size can be
+// reused when the constraints are identical, when an exact size matches the
+// previously measured one, when an at-most size still fits a previously
+// unconstrained measurement, or when a stricter at-most size still contains
+// the previous result.
+func CanUseCachedMeasurement(widthMode MeasureMode, width float64, heightMode MeasureMode, height float64,
+	lastWidthMode MeasureMode, lastWidth float64, lastHeightMode MeasureMode, lastHeight float64,
+	lastComputedWidth, lastComputedHeight, marginRow, marginColumn float64) bool {
+	if lastComputedHeight < 0 || lastComputedWidth < 0 {
+		return false
+	}
 
-func init() {
-	trailing[FlexDirectionColumn] = EdgeBottom
-	trailing[FlexDirectionColumnReverse] = EdgeTop
-	trailing[FlexDirectionRow] = EdgeRight
-	trailing[FlexDirectionRowReverse] = EdgeLeft
-}
+	hasSameWidthSpec := lastWidthMode == widthMode && FloatsEqual(lastWidth, width)
+	hasSameHeightSpec := lastHeightMode == heightMode && FloatsEqual(lastHeight, height)
 
-var pos [4]Edge
+	widthIsCompatible := hasSameWidthSpec ||
+		measureModeSizeIsExactAndMatchesOldMeasuredSize(widthMode, width-marginRow, lastComputedWidth) ||
+		measureModeOldSizeIsUnspecifiedAndStillFits(widthMode, width-marginRow, lastWidthMode, lastComputedWidth) ||
+		measureModeNewMeasureSizeIsStricterAndStillValid(widthMode, width-marginRow, lastWidthMode, lastWidth, lastComputedWidth)
 
-func init() {
-	pos[FlexDirectionColumn] = EdgeTop
-	pos[FlexDirectionColumnReverse] = EdgeBottom
-	pos[FlexDirectionRow] = EdgeLeft
-	pos[FlexDirectionRowReverse] = EdgeRight
+	heightIsCompatible := hasSameHeightSpec ||
+		measureModeSizeIsExactAndMatchesOldMeasuredSize(heightMode, height-marginColumn, lastComputedHeight) ||
+		measureModeOldSizeIsUnspecifiedAndStillFits(heightMode, height-marginColumn, lastHeightMode, lastComputedHeight) ||
+		measureModeNewMeasureSizeIsStricterAndStillValid(heightMode, height-marginColumn, lastHeightMode, lastHeight, lastComputedHeight)
+
+	return widthIsCompatible && heightIsCompatible
 }
 
-var dim [4]Dimension
+// LayoutNodeInternal is a wrapper around NodeLayoutImpl that consults and
+// fills the measurement cache of the node. A layout operation modifies the
+// positions and dimensions of the nodes in the subtree, and the algorithm
+// assumes that each node gets laid out a maximum of one time per tree
+// layout, but multiple measurements may be required to resolve all of the
+// flex dimensions. Layouts and measurements are therefore cached separately.
+//
+// It returns whether the node was laid out or measured again rather than
+// served from the cache.
+func LayoutNodeInternal(ctx *layoutContext, node *Node, availableWidth, availableHeight float64, parentDirection Direction,
+	widthMeasureMode, heightMeasureMode MeasureMode, parentWidth, parentHeight float64, performLayout bool) (bool, error) {
+	layout := &node.layout
 
-func init() {
-	dim[FlexDirectionColumn] = DimensionHeight
-	dim[FlexDirectionColumnReverse] = DimensionHeight
-	dim[FlexDirectionRow] = DimensionWidth
-	dim[FlexDirectionRowReverse] = DimensionWidth
+	needToVisitNode := (node.isDirty && layout.generationCount != ctx.generationCount) ||
+		layout.lastParentDirection != parentDirection
+
+	if needToVisitNode {
+		// Invalidate the cached results.
+		layout.nextCachedMeasurementsIndex = 0
+		layout.cachedLayout.widthMeasureMode = MeasureMode(-1)
+		layout.cachedLayout.heightMeasureMode = MeasureMode(-1)
+		layout.cachedLayout.computedWidth = -1
+		layout.cachedLayout.computedHeight = -1
+	}
+
+	var cachedResults *CachedMeasurement
+
+	// We handle nodes with measure functions specially here because they are
+	// the most expensive to measure, so it's worth avoiding redundant
+	// measurements if at all possible.
+	if node.measure != nil {
+		marginAxisRow, err := MarginForAxis(node, FlexDirectionRow, parentWidth)
+		if err != nil {
+			return false, err
+		}
+		marginAxisColumn, err := MarginForAxis(node, FlexDirectionColumn, parentWidth)
+		if err != nil {
+			return false, err
+		}
+
+		// First, try to use the layout cache.
+		if CanUseCachedMeasurement(widthMeasureMode, availableWidth, heightMeasureMode, availableHeight,
+			layout.cachedLayout.widthMeasureMode, layout.cachedLayout.availableWidth,
+			layout.cachedLayout.heightMeasureMode, layout.cachedLayout.availableHeight,
+			layout.cachedLayout.computedWidth, layout.cachedLayout.computedHeight, marginAxisRow, marginAxisColumn) {
+			cachedResults = &layout.cachedLayout
+		} else {
+			// Try to use the measurement cache.
+			for i := uint32(0); i < layout.nextCachedMeasurementsIndex; i++ {
+				if CanUseCachedMeasurement(widthMeasureMode, availableWidth, heightMeasureMode, availableHeight,
+					layout.cachedMeasurements[i].widthMeasureMode, layout.cachedMeasurements[i].availableWidth,
+					layout.cachedMeasurements[i].heightMeasureMode, layout.cachedMeasurements[i].availableHeight,
+					layout.cachedMeasurements[i].computedWidth, layout.cachedMeasurements[i].computedHeight,
+					marginAxisRow, marginAxisColumn) {
+					cachedResults = &layout.cachedMeasurements[i]
+					break
+				}
+			}
+		}
+	} else if performLayout {
+		if FloatsEqual(layout.cachedLayout.availableWidth, availableWidth) &&
+			FloatsEqual(layout.cachedLayout.availableHeight, availableHeight) &&
+			layout.cachedLayout.widthMeasureMode == widthMeasureMode &&
+			layout.cachedLayout.heightMeasureMode == heightMeasureMode {
+			cachedResults = &layout.cachedLayout
+		}
+	} else {
+		for i := uint32(0); i < layout.nextCachedMeasurementsIndex; i++ {
+			if FloatsEqual(layout.cachedMeasurements[i].availableWidth, availableWidth) &&
+				FloatsEqual(layout.cachedMeasurements[i].availableHeight, availableHeight) &&
+				layout.cachedMeasurements[i].widthMeasureMode == widthMeasureMode &&
+				layout.cachedMeasurements[i].heightMeasureMode == heightMeasureMode {
+				cachedResults = &layout.cachedMeasurements[i]
+				break
+			}
+		}
+	}
+
+	if !needToVisitNode && cachedResults != nil {
+		layout.measuredDimensions[DimensionWidth] = cachedResults.computedWidth
+		layout.measuredDimensions[DimensionHeight] = cachedResults.computedHeight
+	} else {
+		if err := NodeLayoutImpl(ctx, node, availableWidth, availableHeight, parentDirection, widthMeasureMode, heightMeasureMode,
+			parentWidth, parentHeight, performLayout); err != nil {
+			return false, err
+		}
+
+		layout.lastParentDirection = parentDirection
+
+		if cachedResults == nil {
+			if layout.nextCachedMeasurementsIndex == maxCachedResultCount {
+				layout.nextCachedMeasurementsIndex = 0
+			}
+
+			var newCacheEntry *CachedMeasurement
+			if performLayout {
+				// Use the single layout cache entry.
+				newCacheEntry = &layout.cachedLayout
+			} else {
+				// Allocate a new measurement cache entry.
+				newCacheEntry = &layout.cachedMeasurements[layout.nextCachedMeasurementsIndex]
+				layout.nextCachedMeasurementsIndex++
+			}
+
+			newCacheEntry.availableWidth = availableWidth
+			newCacheEntry.availableHeight = availableHeight
+			newCacheEntry.widthMeasureMode = widthMeasureMode
+			newCacheEntry.heightMeasureMode = heightMeasureMode
+			newCacheEntry.computedWidth = layout.measuredDimensions[DimensionWidth]
+			newCacheEntry.computedHeight = layout.measuredDimensions[DimensionHeight]
+		}
+	}
+
+	if performLayout {
+		layout.dimensions[DimensionWidth] = layout.measuredDimensions[DimensionWidth]
+		layout.dimensions[DimensionHeight] = layout.measuredDimensions[DimensionHeight]
+		if node.style.overflow != OverflowVisible {
+			if err := SetContentDimensions(node); err != nil {
+				return false, err
+			}
+		}
+		node.hasNewLayout = true
+		node.isDirty = false
+	}
+
+	layout.generationCount = ctx.generationCount
+	return needToVisitNode || cachedResults == nil, nil
+}
+
+func RoundValueToPixelGrid(value, pointScaleFactor float64, forceCeil, forceFloor bool) float64 {
+	scaledValue := value * pointScaleFactor
+	fractial := math.Mod(scaledValue, 1.0)
+	if fractial < 0 {
+		// math.Mod keeps the sign of negative values, so bring the fraction
+		// into [0, 1) to round them like positive ones.
+		fractial += 1.0
+	}
+	if FloatsEqual(fractial, 0) {
+		// First we check if the value is already rounded
+		scaledValue = scaledValue - fractial
+	} else if FloatsEqual(fractial, 1.0) {
+		scaledValue = scaledValue - fractial + 1.0
+	} else if forceCeil {
+		// Next we check if we need to use forced rounding
+		scaledValue = scaledValue - fractial + 1.0
+	} else if forceFloor {
+		scaledValue = scaledValue - fractial
+	} else {
+		// Finally we just round the value
+		scaledValue = scaledValue - fractial
+		if fractial >= 0.5 {
+			scaledValue += 1.0
+		}
+	}
+	return scaledValue / pointScaleFactor
 }
 
-func FlexDirectionIsRow(direction FlexDirection) bool {
-	return direction == FlexDirectionRow || direction == FlexDirectionRowReverse
+// RoundToPixelGrid snaps the layout of node and its descendants to the pixel
+// grid. Edges rather than sizes are rounded, based on the absolute position
+// of the node, so that adjacent nodes never end up with gaps or overlaps
+// between them.
+func RoundToPixelGrid(node *Node, pointScaleFactor, absoluteLeft, absoluteTop float64) {
+	if pointScaleFactor == 0.0 {
+		return
+	}
+
+	// Always round from the unrounded layout, so that rounding a node whose
+	// layout was cached does not round it twice.
+	if !node.layout.rounded {
+		node.layout.unroundedPosition = node.layout.position
+		node.layout.unroundedDimensions = node.layout.dimensions
+		node.layout.rounded = true
+	}
+	nodeLeft := node.layout.unroundedPosition[EdgeLeft]
+	nodeTop := node.layout.unroundedPosition[EdgeTop]
+
+	nodeWidth := node.layout.unroundedDimensions[DimensionWidth]
+	nodeHeight := node.layout.unroundedDimensions[DimensionHeight]
+
+	absoluteNodeLeft := absoluteLeft + nodeLeft
+	absoluteNodeTop := absoluteTop + nodeTop
+
+	absoluteNodeRight := absoluteNodeLeft + nodeWidth
+	absoluteNodeBottom := absoluteNodeTop + nodeHeight
+
+	// If a node has a custom measure function we never want to round down
+	// its size as this could lead to unwanted text truncation.
+	textRounding := node.measure != nil
+
+	node.layout.position[EdgeLeft] = RoundValueToPixelGrid(nodeLeft, pointScaleFactor, false, textRounding)
+	node.layout.position[EdgeTop] = RoundValueToPixelGrid(nodeTop, pointScaleFactor, false, textRounding)
+
+	// We multiply dimension by scale factor and if the result is close to the
+	// whole number, we don't have any fraction. To verify if the result is
+	// close to whole number we want to check both floor and ceil numbers.
+	hasFractionalWidth := !FloatsEqual(math.Mod(nodeWidth*pointScaleFactor, 1.0), 0) &&
+		!FloatsEqual(math.Mod(nodeWidth*pointScaleFactor, 1.0), 1.0)
+	hasFractionalHeight := !FloatsEqual(math.Mod(nodeHeight*pointScaleFactor, 1.0), 0) &&
+		!FloatsEqual(math.Mod(nodeHeight*pointScaleFactor, 1.0), 1.0)
+
+	node.layout.dimensions[DimensionWidth] =
+		RoundValueToPixelGrid(absoluteNodeRight, pointScaleFactor, textRounding && hasFractionalWidth, textRounding && !hasFractionalWidth) -
+			RoundValueToPixelGrid(absoluteNodeLeft, pointScaleFactor, false, textRounding)
+	node.layout.dimensions[DimensionHeight] =
+		RoundValueToPixelGrid(absoluteNodeBottom, pointScaleFactor, textRounding && hasFractionalHeight, textRounding && !hasFractionalHeight) -
+			RoundValueToPixelGrid(absoluteNodeTop, pointScaleFactor, false, textRounding)
+
+	for _, child := range node.children {
+		RoundToPixelGrid(child, pointScaleFactor, absoluteNodeLeft, absoluteNodeTop)
+	}
 }
 
-func FlexDirectionIsColumn(direction FlexDirection) bool {
-	return direction == FlexDirectionColumn || direction == FlexDirectionColumnReverse
+// restoreUnroundedLayout undoes RoundToPixelGrid on node and its
+// descendants, so that the layout algorithm only sees the layout it computed.
+func restoreUnroundedLayout(node *Node) {
+	if node.layout.rounded {
+		node.layout.position = node.layout.unroundedPosition
+		node.layout.dimensions = node.layout.unroundedDimensions
+		node.layout.rounded = false
+	}
+	for _, child := range node.children {
+		restoreUnroundedLayout(child)
+	}
 }
 
-func LeadingMargin(node *Node, axis FlexDirection, widthSize float64) (float64, error) {
-	if FlexDirectionIsRow(axis) && node.style.margin[EdgeStart].unit != UnitUndefined {
-		return ValueResolve(&node.style.margin[EdgeStart], widthSize), nil
+// CalculateLayout lays out the tree rooted at node within the given
+// available size, filling in the Layout of every node in it. Pass NaN for an
+// unconstrained width or height.
+func CalculateLayout(node *Node, availableWidth, availableHeight float64, parentDirection Direction) error {
+	// Start a new generation. This will force the recursive routine to visit
+	// all dirty nodes at least once. Subsequent visits will be skipped if the
+	// input parameters don't change.
+	ctx := &layoutContext{generationCount: atomic.AddUint32(&generationCounter, 1)}
+	restoreUnroundedLayout(node)
+	node.layout.direction = NodeResolveDirection(node, parentDirection)
+
+	width := availableWidth
+	height := availableHeight
+	widthMeasureMode := MeasureModeUndefined
+	heightMeasureMode := MeasureModeUndefined
+
+	marginAxisRow, err := MarginForAxis(node, FlexDirectionRow, availableWidth)
+	if err != nil {
+		return err
 	}
-	val, err := ComputedEdgeValue(node.style.margin, leading[axis], &Value{value: 0, unit: UnitUndefined})
+	marginAxisColumn, err := MarginForAxis(node, FlexDirectionColumn, availableWidth)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	// The style of the root takes precedence over the available size, which
+	// only applies when the style leaves a dimension open.
+	if IsStyleDimDefined(node, FlexDirectionRow, availableWidth) {
+		width = ValueResolve(&node.style.dimensions[dim[FlexDirectionRow]], availableWidth) + marginAxisRow
+		widthMeasureMode = MeasureModeExactly
+	} else if ValueResolve(&node.style.maxDimensions[DimensionWidth], availableWidth) >= 0.0 {
+		width = ValueResolve(&node.style.maxDimensions[DimensionWidth], availableWidth)
+		widthMeasureMode = MeasureModeAtmost
+	} else if !math.IsNaN(width) {
+		widthMeasureMode = MeasureModeExactly
+	}
+
+	if IsStyleDimDefined(node, FlexDirectionColumn, availableHeight) {
+		height = ValueResolve(&node.style.dimensions[dim[FlexDirectionColumn]], availableHeight) + marginAxisColumn
+		heightMeasureMode = MeasureModeExactly
+	} else if ValueResolve(&node.style.maxDimensions[DimensionHeight], availableHeight) >= 0.0 {
+		height = ValueResolve(&node.style.maxDimensions[DimensionHeight], availableHeight)
+		heightMeasureMode = MeasureModeAtmost
+	} else if !math.IsNaN(height) {
+		heightMeasureMode = MeasureModeExactly
+	}
+
+	// Derive a missing dimension of the root from its aspect ratio.
+	if HasAspectRatio(node) {
+		if widthMeasureMode == MeasureModeExactly && heightMeasureMode != MeasureModeExactly {
+			if height, err = AspectRatioSize(node, FlexDirectionRow, width-marginAxisRow, availableHeight, availableWidth); err != nil {
+				return err
+			}
+			height += marginAxisColumn
+			heightMeasureMode = MeasureModeExactly
+		} else if heightMeasureMode == MeasureModeExactly && widthMeasureMode != MeasureModeExactly {
+			if width, err = AspectRatioSize(node, FlexDirectionColumn, height-marginAxisColumn, availableWidth, availableWidth); err != nil {
+				return err
+			}
+			width += marginAxisRow
+			widthMeasureMode = MeasureModeExactly
+		}
 	}
-	return ValueResolve(val, widthSize), nil
+
+	visited, err := LayoutNodeInternal(ctx, node, width, height, parentDirection, widthMeasureMode, heightMeasureMode,
+		availableWidth, availableHeight, true)
+	if err != nil {
+		return err
+	}
+	if visited {
+		if err := NodeSetPosition(node, node.layout.direction, availableWidth, availableHeight, availableWidth); err != nil {
+			return err
+		}
+	}
+	if node.config != nil {
+		RoundToPixelGrid(node, node.config.pointScaleFactor, 0, 0)
+	}
+	return nil
 }
@@ -0,0 +1,1521 @@
+package yoga
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+)
+
+func calculateLayout(t *testing.T, root *Node) {
+	t.Helper()
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertLayout(t *testing.T, node *Node, left, top, width, height float64) {
+	t.Helper()
+	got := [4]float64{GetLayoutLeft(node), GetLayoutTop(node), GetLayoutWidth(node), GetLayoutHeight(node)}
+	if want := [4]float64{left, top, width, height}; got != want {
+		t.Errorf("layout (left, top, width, height) = %v, want %v", got, want)
+	}
+}
+
+// newSizedChild appends a child of the given size to parent. NaN leaves a
+// dimension undefined.
+func newSizedChild(parent *Node, width, height float64) *Node {
+	child := NewNodeWithConfig(GetConfig(parent))
+	SetWidth(child, width)
+	SetHeight(child, height)
+	InsertChild(parent, child, GetChildCount(parent))
+	return child
+}
+
+func TestCalculateLayoutGrowShrinkAndBasis(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, 100)
+	SetHeight(row, 50)
+	fixed := newSizedChild(row, 20, math.NaN())
+	grown := newSizedChild(row, math.NaN(), math.NaN())
+	SetFlexGrow(grown, 1)
+	calculateLayout(t, row)
+	assertLayout(t, row, 0, 0, 100, 50)
+	assertLayout(t, fixed, 0, 0, 20, 50)
+	assertLayout(t, grown, 20, 0, 80, 50)
+
+	column := NewNode()
+	SetHeight(column, 100)
+	SetWidth(column, 30)
+	first := newSizedChild(column, math.NaN(), math.NaN())
+	SetFlexBasis(first, 90)
+	SetFlexShrink(first, 1)
+	second := newSizedChild(column, math.NaN(), 30)
+	SetFlexShrink(second, 1)
+	calculateLayout(t, column)
+	assertLayout(t, first, 0, 0, 30, 75)
+	assertLayout(t, second, 0, 75, 30, 25)
+}
+
+func TestCalculateLayoutJustifyAndAlign(t *testing.T) {
+	for _, test := range []struct {
+		justify    Justify
+		align      Align
+		firstLeft  float64
+		secondLeft float64
+		top        float64
+		height     float64
+	}{
+		{JustifyFlexStart, AlignFlexStart, 0, 20, 0, 10},
+		{JustifyCenter, AlignCenter, 30, 50, 20, 10},
+		{JustifyFlexEnd, AlignFlexEnd, 60, 80, 40, 10},
+		{JustifySpaceBetween, AlignStretch, 0, 80, 0, 50},
+		{JustifySpaceAround, AlignStretch, 15, 65, 0, 50},
+	} {
+		row := NewNode()
+		SetFlexDirection(row, FlexDirectionRow)
+		SetJustifyContent(row, test.justify)
+		SetAlignItems(row, test.align)
+		SetWidth(row, 100)
+		SetHeight(row, 50)
+		first := newSizedChild(row, 20, math.NaN())
+		second := newSizedChild(row, 20, math.NaN())
+		if test.align != AlignStretch {
+			SetHeight(first, 10)
+			SetHeight(second, 10)
+		}
+		calculateLayout(t, row)
+		assertLayout(t, first, test.firstLeft, test.top, 20, test.height)
+		assertLayout(t, second, test.secondLeft, test.top, 20, test.height)
+	}
+}
+
+func TestCalculateLayoutAlignSelfOverridesAlignItems(t *testing.T) {
+	column := NewNode()
+	SetWidth(column, 100)
+	SetAlignItems(column, AlignFlexEnd)
+	child := newSizedChild(column, 20, 20)
+	SetAlignSelf(child, AlignCenter)
+	calculateLayout(t, column)
+	assertLayout(t, child, 40, 0, 20, 20)
+	assertLayout(t, column, 0, 0, 100, 20)
+}
+
+func TestCalculateLayoutMarginPaddingAndBorder(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetPadding(root, EdgeAll, 10)
+	SetBorder(root, EdgeLeft, 2)
+	child := newSizedChild(root, math.NaN(), 20)
+	SetMargin(child, EdgeAll, 5)
+	calculateLayout(t, root)
+	assertLayout(t, root, 0, 0, 100, 50)
+	assertLayout(t, child, 17, 15, 68, 20)
+}
+
+func TestCalculateLayoutMeasureFunc(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetAlignItems(root, AlignFlexStart)
+	leaf := NewNode()
+	SetMeasureFunc(leaf, func(node *Node, width float64, widthMode MeasureMode, height float64, heightMode MeasureMode) Size {
+		// Text of 120 points that wraps onto lines of 10 points.
+		if widthMode == MeasureModeUndefined || width >= 120 {
+			return Size{width: 120, height: 10}
+		}
+		return Size{width: width, height: 10 * math.Ceil(120/width)}
+	})
+	InsertChild(root, leaf, 0)
+	calculateLayout(t, root)
+	assertLayout(t, leaf, 0, 0, 100, 20)
+	assertLayout(t, root, 0, 0, 100, 20)
+}
+
+func TestCalculateLayoutRootStyleOverridesAvailableSize(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetMaxHeight(root, 50)
+	newSizedChild(root, 10, 80)
+	if err := CalculateLayout(root, 300, 200, DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	assertLayout(t, root, 0, 0, 100, 50)
+
+	// The available size applies to the dimensions the style leaves open.
+	root = NewNode()
+	SetWidthPercent(root, 50)
+	if err := CalculateLayout(root, 300, 200, DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	assertLayout(t, root, 0, 0, 150, 200)
+}
+
+func TestNewNodeDefaults(t *testing.T) {
+	node := NewNode()
+	if GetFlexGrow(node) != 0 || GetFlexShrink(node) != 0 {
+		t.Errorf("flex grow and shrink = %v, %v, want 0, 0", GetFlexGrow(node), GetFlexShrink(node))
+	}
+	if basis := GetFlexBasis(node); basis.unit != UnitUndefined {
+		t.Errorf("flex basis = %v, want undefined", basis)
+	}
+	if width := GetStyleWidth(node); width.unit != UnitUndefined || !math.IsNaN(width.value) {
+		t.Errorf("width = %v, want undefined", width)
+	}
+	if !math.IsNaN(node.style.aspectRatio) {
+		t.Errorf("aspect ratio = %v, want NaN", node.style.aspectRatio)
+	}
+	if GetAlignItems(node) != AlignStretch || GetAlignContent(node) != AlignFlexStart {
+		t.Errorf("align items and content = %v, %v, want stretch, flex-start", GetAlignItems(node), GetAlignContent(node))
+	}
+	if GetFlexDirection(node) != FlexDirectionColumn {
+		t.Errorf("flex direction = %v, want column", GetFlexDirection(node))
+	}
+	if !math.IsNaN(GetLayoutWidth(node)) || !math.IsNaN(node.layout.computedFlexBasis) {
+		t.Errorf("layout width and flex basis = %v, %v, want NaN", GetLayoutWidth(node), node.layout.computedFlexBasis)
+	}
+	if GetConfig(node) == nil {
+		t.Error("GetConfig = nil, want the default config")
+	}
+}
+
+func TestNodeResetAndFree(t *testing.T) {
+	root := NewNode()
+	child := newSizedChild(root, 10, 10)
+	grandchild := newSizedChild(child, 5, 5)
+
+	if err := NodeReset(root); err == nil {
+		t.Error("NodeReset of a node with children succeeded")
+	}
+	if err := NodeReset(child); err == nil {
+		t.Error("NodeReset of a node with a parent succeeded")
+	}
+
+	NodeFree(child)
+	if GetChildCount(root) != 0 || GetParent(child) != nil || GetParent(grandchild) != nil {
+		t.Error("NodeFree did not detach the node from its parent and children")
+	}
+	if width := GetStyleWidth(child); width.unit != UnitUndefined {
+		t.Errorf("width after NodeFree = %v, want undefined", width)
+	}
+
+	config := NewConfig()
+	node := NewNodeWithConfig(config)
+	SetWidth(node, 10)
+	SetFlexGrow(node, 2)
+	if err := NodeReset(node); err != nil {
+		t.Fatal(err)
+	}
+	if GetStyleWidth(node).unit != UnitUndefined || GetFlexGrow(node) != 0 || GetConfig(node) != config {
+		t.Error("NodeReset did not restore the defaults and keep the config")
+	}
+
+	root = NewNode()
+	child = newSizedChild(root, 10, 10)
+	grandchild = newSizedChild(child, 5, 5)
+	NodeFreeRecursive(root)
+	if GetChildCount(root) != 0 || GetChildCount(child) != 0 || GetParent(grandchild) != nil {
+		t.Error("NodeFreeRecursive left nodes attached")
+	}
+}
+
+func TestNodeFreeRelayoutsParent(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	first := newSizedChild(root, 30, 30)
+	second := newSizedChild(root, 30, 30)
+	calculateLayout(t, root)
+	assertLayout(t, second, 30, 0, 30, 30)
+
+	NodeFree(first)
+	if !IsDirty(root) {
+		t.Error("NodeFree did not mark the parent dirty")
+	}
+	calculateLayout(t, root)
+	assertLayout(t, second, 0, 0, 30, 30)
+}
+
+func TestCanUseCachedMeasurement(t *testing.T) {
+	const (
+		undefined = MeasureModeUndefined
+		exactly   = MeasureModeExactly
+		atMost    = MeasureModeAtmost
+	)
+	for _, test := range []struct {
+		name                         string
+		widthMode                    MeasureMode
+		width                        float64
+		lastWidthMode                MeasureMode
+		lastWidth, lastComputedWidth float64
+		want                         bool
+	}{
+		{"same constraints", atMost, 100, atMost, 100, 80, true},
+		{"exact size matching the old result", exactly, 80, atMost, 100, 80, true},
+		{"exact size not matching the old result", exactly, 90, atMost, 100, 80, false},
+		{"at most a size that fits the unconstrained result", atMost, 90, undefined, math.NaN(), 80, true},
+		{"at most a size smaller than the unconstrained result", atMost, 70, undefined, math.NaN(), 80, false},
+		{"stricter at most that still holds the result", atMost, 90, atMost, 100, 80, true},
+		{"stricter at most below the result", atMost, 70, atMost, 100, 80, false},
+		{"looser at most", atMost, 120, atMost, 100, 80, false},
+	} {
+		got := CanUseCachedMeasurement(test.widthMode, test.width, exactly, 10,
+			test.lastWidthMode, test.lastWidth, exactly, 10, test.lastComputedWidth, 10, 0, 0)
+		if got != test.want {
+			t.Errorf("%s: CanUseCachedMeasurement = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMeasureFuncResultsAreCached(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	sibling := newSizedChild(root, math.NaN(), 10)
+	leaf := NewNode()
+	calls := 0
+	SetMeasureFunc(leaf, func(node *Node, width float64, widthMode MeasureMode, height float64, heightMode MeasureMode) Size {
+		calls++
+		return Size{width: 50, height: 20}
+	})
+	InsertChild(root, leaf, 1)
+
+	calculateLayout(t, root)
+	if calls == 0 {
+		t.Fatal("measure function was not called")
+	}
+	assertLayout(t, leaf, 0, 10, 100, 20)
+
+	calls = 0
+	calculateLayout(t, root)
+	if calls != 0 {
+		t.Errorf("measure function called %d times when nothing changed", calls)
+	}
+
+	// A sibling that changes size leaves the constraints of the leaf as they
+	// were.
+	SetHeight(sibling, 30)
+	calculateLayout(t, root)
+	if calls != 0 {
+		t.Errorf("measure function called %d times after a sibling changed", calls)
+	}
+	assertLayout(t, leaf, 0, 30, 100, 20)
+
+	MarkDirty(leaf)
+	calculateLayout(t, root)
+	if calls == 0 {
+		t.Error("measure function not called after MarkDirty")
+	}
+}
+
+// compareLayouts reports the nodes of got whose layout differs from that of
+// the matching node in want.
+func compareLayouts(t *testing.T, path string, got, want *Node) {
+	t.Helper()
+	for _, field := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"left", GetLayoutLeft(got), GetLayoutLeft(want)},
+		{"top", GetLayoutTop(got), GetLayoutTop(want)},
+		{"width", GetLayoutWidth(got), GetLayoutWidth(want)},
+		{"height", GetLayoutHeight(got), GetLayoutHeight(want)},
+	} {
+		if !FloatsEqual(field.got, field.want) {
+			t.Errorf("%s: %s = %v, want %v", path, field.name, field.got, field.want)
+		}
+	}
+	for i := 0; i < GetChildCount(want); i++ {
+		compareLayouts(t, fmt.Sprintf("%s/%d", path, i), GetChild(got, i), GetChild(want, i))
+	}
+}
+
+// newFractionalRow returns a padded row whose three children grow unevenly
+// and hold a nested child each, so that laying it out visits many nodes.
+func newFractionalRow(height float64) *Node {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetPadding(root, EdgeAll, 1.5)
+	for i := 0; i < 3; i++ {
+		child := newSizedChild(root, math.NaN(), height)
+		SetFlexGrow(child, float64(i+1))
+		newSizedChild(child, 3.3, 3.3)
+	}
+	return root
+}
+
+func TestCalculateLayoutConcurrently(t *testing.T) {
+	const trees = 8
+	var wg sync.WaitGroup
+	errs := make([]error, trees)
+	roots := make([]*Node, trees)
+	for i := range roots {
+		roots[i] = newFractionalRow(float64(10 + i))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for pass := 0; pass < 50 && errs[i] == nil; pass++ {
+				SetWidth(roots[i], float64(100+pass%3))
+				errs[i] = CalculateLayout(roots[i], math.NaN(), math.NaN(), DirectionLTR)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, root := range roots {
+		if errs[i] != nil {
+			t.Fatalf("tree %d: %v", i, errs[i])
+		}
+		fresh := newFractionalRow(float64(10 + i))
+		SetWidth(fresh, 101)
+		if err := CalculateLayout(fresh, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+			t.Fatal(err)
+		}
+		compareLayouts(t, fmt.Sprintf("tree %d", i), root, fresh)
+	}
+}
+
+// newRoundingTree returns a tree with fractional sizes and positions. Its
+// first child has a height of firstHeight, which leaves the layout of the
+// nested row in the second child unchanged.
+func newRoundingTree(config *Config, firstHeight float64) (root, first *Node) {
+	root = NewNodeWithConfig(config)
+	SetWidth(root, 100.3)
+	SetPadding(root, EdgeAll, 1.7)
+	first = NewNodeWithConfig(config)
+	SetHeight(first, firstHeight)
+	InsertChild(root, first, 0)
+	second := NewNodeWithConfig(config)
+	SetFlexDirection(second, FlexDirectionRow)
+	SetMargin(second, EdgeLeft, 0.45)
+	SetMargin(second, EdgeTop, 0.3)
+	InsertChild(root, second, 1)
+	for i := 0; i < 3; i++ {
+		child := NewNodeWithConfig(config)
+		SetFlexGrow(child, 1)
+		SetHeight(child, 10.35)
+		SetPadding(child, EdgeLeft, 0.55)
+		SetPadding(child, EdgeTop, 0.2)
+		InsertChild(second, child, i)
+		grandchild := NewNodeWithConfig(config)
+		SetWidth(grandchild, 3.3)
+		SetHeight(grandchild, 3.3)
+		SetMargin(grandchild, EdgeLeft, 0.35)
+		SetMargin(grandchild, EdgeTop, 1.15)
+		InsertChild(child, grandchild, 0)
+	}
+	return root, first
+}
+
+func TestRoundToPixelGridIncrementalLayout(t *testing.T) {
+	config := NewConfig()
+	SetPointScaleFactor(config, 2)
+
+	root, first := newRoundingTree(config, 10.3)
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	for _, height := range []float64{20.4, 10.3, 7.15} {
+		SetHeight(first, height)
+		if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+			t.Fatal(err)
+		}
+		fresh, _ := newRoundingTree(config, height)
+		if err := CalculateLayout(fresh, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+			t.Fatal(err)
+		}
+		compareLayouts(t, fmt.Sprintf("height %v: root", height), root, fresh)
+	}
+}
+
+func TestSetPointScaleFactor(t *testing.T) {
+	config := NewConfig()
+	if GetPointScaleFactor(config) != 1 {
+		t.Errorf("default point scale factor = %v, want 1", GetPointScaleFactor(config))
+	}
+	if err := SetPointScaleFactor(config, -1); err == nil {
+		t.Error("SetPointScaleFactor(-1) succeeded")
+	}
+	if err := SetPointScaleFactor(config, 2); err != nil || GetPointScaleFactor(config) != 2 {
+		t.Errorf("SetPointScaleFactor(2) = %v, factor %v", err, GetPointScaleFactor(config))
+	}
+}
+
+func TestRoundValueToPixelGrid(t *testing.T) {
+	for _, test := range []struct {
+		value, scale          float64
+		forceCeil, forceFloor bool
+		want                  float64
+	}{
+		{1.4, 1, false, false, 1},
+		{1.5, 1, false, false, 2},
+		{1.2, 2, false, false, 1},
+		{1.3, 2, false, false, 1.5},
+		{1.1, 1, true, false, 2},
+		{1.9, 1, false, true, 1},
+		{2, 1, true, false, 2},
+		{-0.7, 1, false, false, -1},
+		{-10.6, 1, false, false, -11},
+		{-1.5, 1, false, false, -1},
+		{-1.3, 2, false, false, -1.5},
+		{-0.2, 1, true, false, 0},
+		{-0.2, 1, false, true, -1},
+		{-2, 1, true, false, -2},
+	} {
+		got := RoundValueToPixelGrid(test.value, test.scale, test.forceCeil, test.forceFloor)
+		if !FloatsEqual(got, test.want) {
+			t.Errorf("RoundValueToPixelGrid(%v, %v, %v, %v) = %v, want %v",
+				test.value, test.scale, test.forceCeil, test.forceFloor, got, test.want)
+		}
+	}
+}
+
+func TestRoundToPixelGridLeavesNoGaps(t *testing.T) {
+	for _, scale := range []float64{1, 2} {
+		config := NewConfig()
+		SetPointScaleFactor(config, scale)
+		row := NewNodeWithConfig(config)
+		SetFlexDirection(row, FlexDirectionRow)
+		SetWidth(row, 100.3)
+		SetHeight(row, 10)
+		for i := 0; i < 3; i++ {
+			SetFlexGrow(newSizedChild(row, math.NaN(), math.NaN()), 1)
+		}
+		calculateLayout(t, row)
+
+		right := 0.0
+		for i := 0; i < GetChildCount(row); i++ {
+			child := GetChild(row, i)
+			if GetLayoutLeft(child) != right {
+				t.Errorf("scale %v: child %d left = %v, want %v", scale, i, GetLayoutLeft(child), right)
+			}
+			for _, v := range []float64{GetLayoutLeft(child), GetLayoutWidth(child)} {
+				if !FloatsEqual(math.Mod(v*scale, 1), 0) {
+					t.Errorf("scale %v: child %d has %v off the pixel grid", scale, i, v)
+				}
+			}
+			right = GetLayoutLeft(child) + GetLayoutWidth(child)
+		}
+		if !FloatsEqual(right, GetLayoutWidth(row)) {
+			t.Errorf("scale %v: children end at %v, want %v", scale, right, GetLayoutWidth(row))
+		}
+	}
+
+	config := NewConfig()
+	SetPointScaleFactor(config, 0)
+	row := NewNodeWithConfig(config)
+	SetWidth(row, 100.3)
+	calculateLayout(t, row)
+	if GetLayoutWidth(row) != 100.3 {
+		t.Errorf("width without rounding = %v, want 100.3", GetLayoutWidth(row))
+	}
+}
+
+func TestRoundToPixelGridDoesNotTruncateText(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetAlignItems(root, AlignFlexStart)
+	SetPadding(root, EdgeLeft, 0.1)
+	text := NewNode()
+	SetMeasureFunc(text, func(node *Node, width float64, widthMode MeasureMode, height float64, heightMode MeasureMode) Size {
+		return Size{width: 10.2, height: 10}
+	})
+	InsertChild(root, text, 0)
+	calculateLayout(t, root)
+	if GetLayoutWidth(text) != 11 {
+		t.Errorf("text width = %v, want 11", GetLayoutWidth(text))
+	}
+}
+
+func TestRoundToPixelGridNegativePositions(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	child := newSizedChild(root, 20, 20)
+	SetMargin(child, EdgeLeft, -10.6)
+	SetMargin(child, EdgeTop, -0.7)
+	calculateLayout(t, root)
+
+	assertLayout(t, child, -11, -1, 20, 20)
+}
+
+// newBaselineTree returns a column root holding a row that aligns its
+// children to their baselines. The baseline of the second child comes from
+// its own child, which sits below its padding.
+func newBaselineTree() (root, row, padded *Node) {
+	root = NewNode()
+	SetWidth(root, 200)
+	SetHeight(root, 200)
+	row = NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetAlignItems(row, AlignBaseLine)
+	InsertChild(root, row, 0)
+	first := NewNode()
+	SetWidth(first, 10)
+	SetHeight(first, 50)
+	InsertChild(row, first, 0)
+	padded = NewNode()
+	SetWidth(padded, 10)
+	SetPadding(padded, EdgeTop, 30)
+	InsertChild(row, padded, 1)
+	grandchild := NewNode()
+	SetHeight(grandchild, 40)
+	InsertChild(padded, grandchild, 0)
+	return root, row, padded
+}
+
+func TestBaselineOfNestedContainerWhenMeasured(t *testing.T) {
+	root, row, padded := newBaselineTree()
+	for _, width := range []float64{200, 201, 200} {
+		SetWidth(root, width)
+		if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+			t.Fatal(err)
+		}
+		if got := GetLayoutHeight(row); got != 70 {
+			t.Errorf("root width %v: row height = %v, want 70", width, got)
+		}
+		if got := GetLayoutTop(padded); got != 0 {
+			t.Errorf("root width %v: padded child top = %v, want 0", width, got)
+		}
+	}
+
+	// Moving the baseline of the nested container changes the height of the
+	// row on the next layout, as it does on a fresh one.
+	SetPadding(padded, EdgeTop, 20)
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetLayoutHeight(row); got != 60 {
+		t.Errorf("row height after padding change = %v, want 60", got)
+	}
+	if got := GetLayoutTop(padded); got != 0 {
+		t.Errorf("padded child top after padding change = %v, want 0", got)
+	}
+}
+
+func TestBaselineOfNestedBaselineContainers(t *testing.T) {
+	_, inner, _ := newBaselineTree()
+	root := NewNode()
+	SetWidth(root, 200)
+	outer := NewNode()
+	SetFlexDirection(outer, FlexDirectionRow)
+	SetAlignItems(outer, AlignBaseLine)
+	InsertChild(root, outer, 0)
+	short := NewNode()
+	SetWidth(short, 10)
+	SetHeight(short, 20)
+	InsertChild(outer, short, 0)
+	RemoveChild(GetParent(inner), inner)
+	InsertChild(outer, inner, 1)
+
+	for _, width := range []float64{200, 150, 200} {
+		SetWidth(root, width)
+		if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+			t.Fatal(err)
+		}
+		if got := GetLayoutHeight(outer); got != 70 {
+			t.Errorf("root width %v: outer height = %v, want 70", width, got)
+		}
+		if got := GetLayoutTop(short); got != 50 {
+			t.Errorf("root width %v: short child top = %v, want 50", width, got)
+		}
+		if got := GetLayoutTop(GetChild(inner, 0)); got != 20 {
+			t.Errorf("root width %v: first inner child top = %v, want 20", width, got)
+		}
+	}
+}
+
+func TestBaselineFunc(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetAlignItems(row, AlignBaseLine)
+	SetWidth(row, 100)
+	small := newSizedChild(row, 20, 20)
+	large := newSizedChild(row, 20, 40)
+	SetBaselineFunc(small, func(node *Node, width, height float64) float64 {
+		return height / 2
+	})
+	if GetBaselineFunc(small) == nil || GetBaselineFunc(large) != nil {
+		t.Fatal("GetBaselineFunc does not return the function set")
+	}
+	calculateLayout(t, row)
+
+	// The baseline of large is its bottom edge, 30 below that of small,
+	// whose lower half hangs below it.
+	assertLayout(t, small, 0, 30, 20, 20)
+	assertLayout(t, large, 20, 0, 20, 40)
+	assertLayout(t, row, 0, 0, 100, 50)
+
+	SetBaselineFunc(small, func(node *Node, width, height float64) float64 {
+		return math.NaN()
+	})
+	SetWidth(row, 101)
+	if err := CalculateLayout(row, math.NaN(), math.NaN(), DirectionLTR); err == nil {
+		t.Error("CalculateLayout succeeded with a baseline function returning NaN")
+	}
+}
+
+func TestBaselineFallsBackToFirstChild(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, 100)
+	label := newSizedChild(row, 20, 10)
+	SetAlignSelf(label, AlignBaseLine)
+
+	// The baseline of box comes from its first child, whose own baseline is
+	// given by a function.
+	box := newSizedChild(row, 30, math.NaN())
+	SetAlignSelf(box, AlignBaseLine)
+	SetPadding(box, EdgeTop, 5)
+	text := newSizedChild(box, math.NaN(), 20)
+	SetBaselineFunc(text, func(node *Node, width, height float64) float64 {
+		return 15
+	})
+	newSizedChild(box, math.NaN(), 10)
+	calculateLayout(t, row)
+
+	assertLayout(t, label, 0, 10, 20, 10)
+	assertLayout(t, box, 20, 0, 30, 35)
+	assertLayout(t, text, 0, 5, 30, 20)
+	assertLayout(t, row, 0, 0, 100, 35)
+}
+
+func TestAbsolutePositioning(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 200)
+	SetHeight(root, 100)
+	SetPadding(root, EdgeAll, 10)
+	SetBorder(root, EdgeAll, 5)
+	inFlow := newSizedChild(root, 50, 20)
+
+	inset := newSizedChild(root, 30, 30)
+	SetPositionType(inset, PositionTypeAbsolute)
+	SetPosition(inset, EdgeRight, 10)
+	SetPosition(inset, EdgeBottom, 5)
+
+	stretched := newSizedChild(root, math.NaN(), math.NaN())
+	SetPositionType(stretched, PositionTypeAbsolute)
+	SetPosition(stretched, EdgeLeft, 0)
+	SetPosition(stretched, EdgeRight, 0)
+	SetPosition(stretched, EdgeTop, 20)
+	SetPosition(stretched, EdgeBottom, 40)
+
+	// Percentages resolve against the padding box of root, 190 by 90.
+	percent := newSizedChild(root, 10, 10)
+	SetPositionType(percent, PositionTypeAbsolute)
+	SetPositionPercent(percent, EdgeLeft, 50)
+	SetPositionPercent(percent, EdgeTop, 10)
+	calculateLayout(t, root)
+
+	assertLayout(t, inFlow, 15, 15, 50, 20)
+	assertLayout(t, inset, 155, 60, 30, 30)
+	assertLayout(t, stretched, 5, 25, 190, 30)
+	assertLayout(t, percent, 100, 14, 10, 10)
+}
+
+func TestAbsoluteChildAlignedWithoutInsets(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetJustifyContent(root, JustifyCenter)
+	SetAlignItems(root, AlignFlexEnd)
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	SetPadding(root, EdgeAll, 10)
+	newSizedChild(root, 30, 30)
+	badge := newSizedChild(root, 20, 20)
+	SetPositionType(badge, PositionTypeAbsolute)
+	calculateLayout(t, root)
+
+	// The badge is not part of the flex line, so the in-flow child alone is
+	// centered. The badge itself is aligned within the content box.
+	assertLayout(t, GetChild(root, 0), 35, 60, 30, 30)
+	assertLayout(t, badge, 40, 70, 20, 20)
+}
+
+func TestAbsoluteChildWithoutInsetsInPaddedParent(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		flexDirection FlexDirection
+		direction     Direction
+		left, top     float64
+	}{
+		{"column", FlexDirectionColumn, DirectionLTR, 10, 10},
+		{"column-reverse", FlexDirectionColumnReverse, DirectionLTR, 10, 70},
+		{"row", FlexDirectionRow, DirectionLTR, 10, 10},
+		{"rtl row", FlexDirectionRow, DirectionRTL, 70, 10},
+	} {
+		root := NewNode()
+		SetFlexDirection(root, test.flexDirection)
+		SetWidth(root, 100)
+		SetHeight(root, 100)
+		SetPadding(root, EdgeAll, 10)
+		child := newSizedChild(root, 20, 20)
+		SetPositionType(child, PositionTypeAbsolute)
+		if err := CalculateLayout(root, math.NaN(), math.NaN(), test.direction); err != nil {
+			t.Fatal(err)
+		}
+		if left, top := GetLayoutLeft(child), GetLayoutTop(child); left != test.left || top != test.top {
+			t.Errorf("%s: child at (%v, %v), want (%v, %v)", test.name, left, top, test.left, test.top)
+		}
+	}
+}
+
+// newWrappedRow returns a wrapping row of 100 by 100 holding four children
+// of 40 by 20, two per line.
+func newWrappedRow() *Node {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetFlexWrap(row, WrapWrap)
+	SetWidth(row, 100)
+	SetHeight(row, 100)
+	for i := 0; i < 4; i++ {
+		newSizedChild(row, 40, 20)
+	}
+	return row
+}
+
+func TestFlexWrapBreaksLines(t *testing.T) {
+	row := newWrappedRow()
+	calculateLayout(t, row)
+	for i, want := range [][2]float64{{0, 0}, {40, 0}, {0, 20}, {40, 20}} {
+		child := GetChild(row, i)
+		assertLayout(t, child, want[0], want[1], 40, 20)
+		if wantLine := uint32(i / 2); child.lineIndex != wantLine {
+			t.Errorf("child %d line = %d, want %d", i, child.lineIndex, wantLine)
+		}
+	}
+
+	// Without a height the row is as tall as its lines.
+	SetHeightAuto(row)
+	calculateLayout(t, row)
+	assertLayout(t, row, 0, 0, 100, 40)
+}
+
+func TestAlignContent(t *testing.T) {
+	for _, test := range []struct {
+		align      Align
+		first      float64
+		second     float64
+		lineHeight float64
+	}{
+		{AlignFlexStart, 0, 20, 20},
+		{AlignCenter, 30, 50, 20},
+		{AlignFlexEnd, 60, 80, 20},
+		{AlignStretch, 0, 50, 50},
+		{AlignSpaceBetween, 0, 80, 20},
+		{AlignSpaceAround, 15, 65, 20},
+	} {
+		row := newWrappedRow()
+		SetAlignContent(row, test.align)
+		// Children without a height stretch to the height of their line.
+		SetHeightAuto(GetChild(row, 1))
+		calculateLayout(t, row)
+		assertLayout(t, GetChild(row, 0), 0, test.first, 40, 20)
+		assertLayout(t, GetChild(row, 1), 40, test.first, 40, test.lineHeight)
+		assertLayout(t, GetChild(row, 2), 0, test.second, 40, 20)
+	}
+}
+
+func TestFlexWrapReverse(t *testing.T) {
+	row := newWrappedRow()
+	SetFlexWrap(row, WrapWrapReverse)
+	calculateLayout(t, row)
+	for i, want := range [][2]float64{{0, 80}, {40, 80}, {0, 60}, {40, 60}} {
+		assertLayout(t, GetChild(row, i), want[0], want[1], 40, 20)
+	}
+
+	SetAlignContent(row, AlignCenter)
+	calculateLayout(t, row)
+	assertLayout(t, GetChild(row, 0), 0, 50, 40, 20)
+	assertLayout(t, GetChild(row, 2), 0, 30, 40, 20)
+
+	column := NewNode()
+	SetFlexWrap(column, WrapWrapReverse)
+	SetWidth(column, 100)
+	SetHeight(column, 30)
+	first := newSizedChild(column, 10, 20)
+	second := newSizedChild(column, 10, 20)
+	calculateLayout(t, column)
+	assertLayout(t, first, 90, 0, 10, 20)
+	assertLayout(t, second, 80, 0, 10, 20)
+}
+
+func TestRTLDirection(t *testing.T) {
+	root := NewNode()
+	SetFlexDirection(root, FlexDirectionRow)
+	SetWidth(root, 100)
+	SetHeight(root, 50)
+	SetPadding(root, EdgeStart, 10)
+	SetBorder(root, EdgeEnd, 5)
+	first := newSizedChild(root, 20, math.NaN())
+	SetMargin(first, EdgeStart, 3)
+	second := newSizedChild(root, 20, math.NaN())
+	ltr := NewNode()
+	SetDirection(ltr, DirectionLTR)
+	InsertChild(second, ltr, 0)
+	abs := newSizedChild(root, 10, 10)
+	SetPositionType(abs, PositionTypeAbsolute)
+	SetPosition(abs, EdgeStart, 1)
+
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionRTL); err != nil {
+		t.Fatal(err)
+	}
+	if GetLayoutDirection(root) != DirectionRTL || GetLayoutDirection(first) != DirectionRTL {
+		t.Errorf("directions = %v, %v, want inherited rtl", GetLayoutDirection(root), GetLayoutDirection(first))
+	}
+	if GetLayoutDirection(ltr) != DirectionLTR {
+		t.Errorf("direction of ltr child = %v, want ltr", GetLayoutDirection(ltr))
+	}
+
+	// Rows run from the right, where the start edges are.
+	assertLayout(t, first, 67, 0, 20, 50)
+	assertLayout(t, second, 47, 0, 20, 50)
+	assertLayout(t, abs, 89, 0, 10, 10)
+	for _, test := range []struct {
+		name string
+		get  func(*Node, Edge) (float64, error)
+		node *Node
+		edge Edge
+		want float64
+	}{
+		{"margin start", GetLayoutMargin, first, EdgeStart, 3},
+		{"margin right", GetLayoutMargin, first, EdgeRight, 3},
+		{"margin left", GetLayoutMargin, first, EdgeLeft, 0},
+		{"padding start", GetLayoutPadding, root, EdgeStart, 10},
+		{"padding right", GetLayoutPadding, root, EdgeRight, 10},
+		{"border end", GetLayoutBorder, root, EdgeEnd, 5},
+		{"border left", GetLayoutBorder, root, EdgeLeft, 5},
+	} {
+		if got, err := test.get(test.node, test.edge); err != nil || got != test.want {
+			t.Errorf("%s = %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	assertLayout(t, first, 13, 0, 20, 50)
+	assertLayout(t, second, 33, 0, 20, 50)
+}
+
+func TestAutoMargins(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetJustifyContent(row, JustifyCenter)
+	SetAlignItems(row, AlignFlexStart)
+	SetWidth(row, 100)
+	SetHeight(row, 50)
+	first := newSizedChild(row, 20, 10)
+	logout := newSizedChild(row, 20, 10)
+	SetMarginAuto(logout, EdgeStart)
+	SetMarginAuto(logout, EdgeVertical)
+	calculateLayout(t, row)
+
+	// Auto margins take all of the free space, overriding the justification
+	// and alignment of the row.
+	assertLayout(t, first, 0, 0, 20, 10)
+	assertLayout(t, logout, 80, 20, 20, 10)
+	if margin, _ := GetMargin(logout, EdgeStart); margin.unit != UnitAuto {
+		t.Errorf("margin start unit = %v, want auto", margin.unit)
+	}
+
+	SetMarginAuto(first, EdgeLeft)
+	SetMarginAuto(first, EdgeRight)
+	calculateLayout(t, row)
+	assertLayout(t, first, 20, 0, 20, 10)
+	assertLayout(t, logout, 80, 20, 20, 10)
+}
+
+func TestAutoDimensionsAndFlexBasis(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, 100)
+	SetHeight(row, 50)
+	child := newSizedChild(row, 30, 20)
+	SetWidthAuto(child)
+	SetHeightAuto(child)
+	SetFlexBasisAuto(child)
+	inner := newSizedChild(child, 25, 10)
+	calculateLayout(t, row)
+
+	if GetStyleWidth(child).unit != UnitAuto || GetHeight(child).unit != UnitAuto || GetFlexBasis(child).unit != UnitAuto {
+		t.Error("width, height and flex basis are not auto")
+	}
+	// An auto flex basis and width size the child to its content, and an
+	// auto height lets it stretch.
+	assertLayout(t, child, 0, 0, 25, 50)
+	assertLayout(t, inner, 0, 0, 25, 10)
+}
+
+func TestGap(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, 100)
+	SetGap(row, GutterColumn, 10)
+	for i := 0; i < 3; i++ {
+		newSizedChild(row, 20, 10)
+	}
+	calculateLayout(t, row)
+	for i, left := range []float64{0, 30, 60} {
+		assertLayout(t, GetChild(row, i), left, 0, 20, 10)
+	}
+
+	// Percentages resolve against the inner size of the container on the
+	// axis of the gap.
+	SetGapPercent(row, GutterColumn, 20)
+	calculateLayout(t, row)
+	assertLayout(t, GetChild(row, 2), 80, 0, 20, 10)
+
+	// Gaps take their share of the line before the items grow.
+	SetGap(row, GutterColumn, 5)
+	SetFlexGrow(GetChild(row, 0), 1)
+	calculateLayout(t, row)
+	assertLayout(t, GetChild(row, 0), 0, 0, 50, 10)
+	assertLayout(t, GetChild(row, 2), 80, 0, 20, 10)
+}
+
+func TestGapBetweenLines(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetFlexWrap(row, WrapWrap)
+	SetWidth(row, 50)
+	SetGap(row, GutterAll, 10)
+	SetGap(row, GutterRow, 5)
+	for i := 0; i < 3; i++ {
+		newSizedChild(row, 20, 10)
+	}
+	calculateLayout(t, row)
+	assertLayout(t, GetChild(row, 0), 0, 0, 20, 10)
+	assertLayout(t, GetChild(row, 1), 30, 0, 20, 10)
+	assertLayout(t, GetChild(row, 2), 0, 15, 20, 10)
+	assertLayout(t, row, 0, 0, 50, 25)
+
+	if gap := GetGap(row, GutterColumn); gap.value != 10 || gap.unit != UnitPixel {
+		t.Errorf("column gap = %v, want the gap of all gutters", gap)
+	}
+	if gap := GetGap(row, GutterRow); gap.value != 5 {
+		t.Errorf("row gap = %v, want 5", gap)
+	}
+}
+
+func TestDisplayNone(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, 100)
+	SetHeight(row, 50)
+	SetGap(row, GutterColumn, 10)
+	first := newSizedChild(row, 20, 20)
+	hidden := newSizedChild(row, 30, 30)
+	hiddenChild := newSizedChild(hidden, 10, 10)
+	last := newSizedChild(row, math.NaN(), 20)
+	SetFlexGrow(last, 1)
+	calculateLayout(t, row)
+	assertLayout(t, hidden, 30, 0, 30, 30)
+
+	// Hidden nodes take no space and add no gap.
+	SetDisplay(hidden, DisplayNone)
+	if GetDisplay(hidden) != DisplayNone {
+		t.Fatalf("display = %v, want none", GetDisplay(hidden))
+	}
+	calculateLayout(t, row)
+	assertLayout(t, first, 0, 0, 20, 20)
+	assertLayout(t, hidden, 0, 0, 0, 0)
+	assertLayout(t, hiddenChild, 0, 0, 0, 0)
+	assertLayout(t, last, 30, 0, 70, 20)
+
+	SetDisplay(hidden, DisplayFlex)
+	calculateLayout(t, row)
+	assertLayout(t, hidden, 30, 0, 30, 30)
+	assertLayout(t, last, 70, 0, 30, 20)
+}
+
+func TestDisplayContents(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, 100)
+	SetHeight(row, 50)
+	first := newSizedChild(row, 20, 20)
+	contents := newSizedChild(row, 50, 50)
+	SetDisplay(contents, DisplayContents)
+	SetPadding(contents, EdgeAll, 5)
+	inner := newSizedChild(contents, math.NaN(), 10)
+	SetFlexGrow(inner, 1)
+	innerFixed := newSizedChild(contents, 10, 10)
+	last := newSizedChild(row, 20, 20)
+	calculateLayout(t, row)
+
+	// The children of contents are flex items of row, positioned relative
+	// to row, and contents itself takes no space.
+	assertLayout(t, first, 0, 0, 20, 20)
+	assertLayout(t, inner, 20, 0, 50, 10)
+	assertLayout(t, innerFixed, 70, 0, 10, 10)
+	assertLayout(t, last, 80, 0, 20, 20)
+	assertLayout(t, contents, 0, 0, 0, 0)
+}
+
+func TestSpaceEvenly(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetJustifyContent(row, JustifySpaceEvenly)
+	SetAlignItems(row, AlignFlexStart)
+	SetWidth(row, 100)
+	SetHeight(row, 50)
+	first := newSizedChild(row, 20, 10)
+	second := newSizedChild(row, 20, 10)
+	calculateLayout(t, row)
+	assertLayout(t, first, 20, 0, 20, 10)
+	assertLayout(t, second, 60, 0, 20, 10)
+
+	wrapped := newWrappedRow()
+	SetAlignContent(wrapped, AlignSpaceEvenly)
+	calculateLayout(t, wrapped)
+	assertLayout(t, GetChild(wrapped, 0), 0, 20, 40, 20)
+	assertLayout(t, GetChild(wrapped, 2), 0, 60, 40, 20)
+
+	// A single line has no space between lines to distribute and is placed
+	// in the middle of the container.
+	SetWidth(wrapped, 200)
+	calculateLayout(t, wrapped)
+	assertLayout(t, GetChild(wrapped, 3), 120, 40, 40, 20)
+}
+
+func TestStaticAndRelativePositions(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	SetPadding(root, EdgeAll, 10)
+	static := newSizedChild(root, 50, 50)
+	SetPositionType(static, PositionTypeStatic)
+	SetPosition(static, EdgeLeft, 20)
+	SetMargin(static, EdgeTop, 5)
+	relative := newSizedChild(root, 20, 20)
+	SetPosition(relative, EdgeLeft, 7)
+	SetPosition(relative, EdgeBottom, 3)
+	sibling := newSizedChild(root, 20, 20)
+
+	// The absolute grandchild is placed against root, as static does not
+	// form a containing block.
+	abs := newSizedChild(static, 10, 10)
+	SetPositionType(abs, PositionTypeAbsolute)
+	SetPosition(abs, EdgeRight, 0)
+	SetPosition(abs, EdgeTop, 0)
+	calculateLayout(t, root)
+
+	if GetPositionType(static) != PositionTypeStatic {
+		t.Errorf("position type = %v, want static", GetPositionType(static))
+	}
+	assertLayout(t, static, 10, 15, 50, 50)
+	assertLayout(t, abs, 80, -15, 10, 10)
+	assertLayout(t, relative, 17, 62, 20, 20)
+	assertLayout(t, sibling, 10, 85, 20, 20)
+}
+
+func TestAspectRatio(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	stretched := newSizedChild(root, math.NaN(), math.NaN())
+	SetAspectRatio(stretched, 2)
+	fixedHeight := newSizedChild(root, math.NaN(), 20)
+	SetAspectRatio(fixedHeight, 0.5)
+	SetAlignSelf(fixedHeight, AlignFlexStart)
+	clamped := newSizedChild(root, math.NaN(), math.NaN())
+	SetAspectRatio(clamped, 1)
+	SetMaxHeight(clamped, 30)
+	calculateLayout(t, root)
+
+	if GetAspectRatio(stretched) != 2 || GetLayoutAspectRatio(stretched) != 2 {
+		t.Errorf("aspect ratio = %v, want 2", GetAspectRatio(stretched))
+	}
+	assertLayout(t, stretched, 0, 0, 100, 50)
+	assertLayout(t, fixedHeight, 0, 50, 10, 20)
+	// The max height wins over stretching, and the width follows it.
+	assertLayout(t, clamped, 0, 70, 30, 30)
+	assertLayout(t, root, 0, 0, 100, 100)
+}
+
+func TestAspectRatioWithFlexBasis(t *testing.T) {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetAlignItems(row, AlignFlexStart)
+	SetWidth(row, 100)
+	grown := newSizedChild(row, math.NaN(), math.NaN())
+	SetFlexGrow(grown, 1)
+	SetAspectRatio(grown, 4)
+	based := newSizedChild(row, math.NaN(), math.NaN())
+	SetFlexBasis(based, 20)
+	SetAspectRatio(based, 0.5)
+	calculateLayout(t, row)
+	assertLayout(t, grown, 0, 0, 80, 20)
+	assertLayout(t, based, 80, 0, 20, 40)
+	assertLayout(t, row, 0, 0, 100, 40)
+
+	// The root derives its height from its width too.
+	SetAspectRatio(row, 2)
+	calculateLayout(t, row)
+	assertLayout(t, row, 0, 0, 100, 50)
+}
+
+func TestAspectRatioOfMeasuredLeaf(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetAlignItems(root, AlignFlexStart)
+	image := newSizedChild(root, 60, math.NaN())
+	SetAspectRatio(image, 1.5)
+	SetMeasureFunc(image, func(node *Node, width float64, widthMode MeasureMode, height float64, heightMode MeasureMode) Size {
+		return Size{width: 10, height: 10}
+	})
+	calculateLayout(t, root)
+	assertLayout(t, image, 0, 0, 60, 40)
+}
+
+// newRow returns a row of the given width holding one child per entry of
+// configure, which is called to style it.
+func newRow(width float64, configure ...func(child *Node)) *Node {
+	row := NewNode()
+	SetFlexDirection(row, FlexDirectionRow)
+	SetWidth(row, width)
+	SetHeight(row, 100)
+	for i, configureChild := range configure {
+		child := NewNode()
+		configureChild(child)
+		InsertChild(row, child, i)
+	}
+	return row
+}
+
+func layoutWidths(t *testing.T, node *Node) []float64 {
+	t.Helper()
+	if err := CalculateLayout(node, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	var widths []float64
+	for i := 0; i < GetChildCount(node); i++ {
+		widths = append(widths, GetLayoutWidth(GetChild(node, i)))
+	}
+	return widths
+}
+
+func TestMinMaxDimensions(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		row  *Node
+		want []float64
+	}{
+		{
+			name: "min wins over a smaller max",
+			row: newRow(200, func(child *Node) {
+				SetWidth(child, 50)
+				SetMinWidth(child, 60)
+				SetMaxWidth(child, 40)
+			}),
+			want: []float64{60},
+		},
+		{
+			name: "min wins over a smaller max when growing",
+			row: newRow(200, func(child *Node) {
+				SetFlexGrow(child, 1)
+				SetMinWidth(child, 60)
+				SetMaxWidth(child, 40)
+			}),
+			want: []float64{60},
+		},
+		{
+			name: "grown items frozen at their max",
+			row: newRow(300, func(child *Node) {
+				SetFlexGrow(child, 1)
+				SetFlexBasis(child, 0)
+				SetMaxWidth(child, 50)
+			}, func(child *Node) {
+				SetFlexGrow(child, 1)
+				SetFlexBasis(child, 0)
+			}, func(child *Node) {
+				SetFlexGrow(child, 1)
+				SetFlexBasis(child, 0)
+			}),
+			want: []float64{50, 125, 125},
+		},
+		{
+			name: "min dimensions clamp the flex basis before growing",
+			row: newRow(300, func(child *Node) {
+				SetFlexGrow(child, 1)
+				SetFlexBasis(child, 0)
+				SetMinWidth(child, 150)
+			}, func(child *Node) {
+				SetFlexGrow(child, 2)
+				SetFlexBasis(child, 0)
+			}),
+			want: []float64{200, 100},
+		},
+		{
+			name: "shrunk items frozen at their min",
+			row: newRow(300, func(child *Node) {
+				SetFlexShrink(child, 1)
+				SetFlexBasis(child, 200)
+				SetMinWidth(child, 150)
+			}, func(child *Node) {
+				SetFlexShrink(child, 1)
+				SetFlexBasis(child, 200)
+			}, func(child *Node) {
+				SetFlexShrink(child, 1)
+				SetFlexBasis(child, 200)
+			}),
+			want: []float64{150, 75, 75},
+		},
+		{
+			name: "max dimensions clamp the flex basis before shrinking",
+			row: newRow(100, func(child *Node) {
+				SetFlexShrink(child, 1)
+				SetFlexBasis(child, 100)
+				SetMaxWidth(child, 30)
+			}, func(child *Node) {
+				SetFlexShrink(child, 1)
+				SetFlexBasis(child, 100)
+			}),
+			want: []float64{23, 77},
+		},
+	} {
+		got := layoutWidths(t, test.row)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: widths = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMinMaxPercentResolveAgainstParentContentBox(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 200)
+	SetHeight(root, 100)
+	SetPadding(root, EdgeHorizontal, 25)
+	SetPadding(root, EdgeVertical, 10)
+	SetBorder(root, EdgeAll, 5)
+	wide := NewNode()
+	SetMaxWidthPercent(wide, 50)
+	SetMinHeightPercent(wide, 20)
+	InsertChild(root, wide, 0)
+	narrow := NewNode()
+	SetWidth(narrow, 10)
+	SetMinWidthPercent(narrow, 10)
+	SetHeight(narrow, 60)
+	SetMaxHeightPercent(narrow, 50)
+	InsertChild(root, narrow, 1)
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+
+	// The content box of root is 140 by 70.
+	for _, test := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"max width", GetLayoutWidth(wide), 70},
+		{"min height", GetLayoutHeight(wide), 14},
+		{"min width", GetLayoutWidth(narrow), 14},
+		{"max height", GetLayoutHeight(narrow), 35},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestScrollContentSize(t *testing.T) {
+	list := NewNode()
+	SetOverflow(list, OverflowScroll)
+	SetWidth(list, 100)
+	SetHeight(list, 100)
+	SetPadding(list, EdgeAll, 5)
+	var rows []*Node
+	for i := 0; i < 3; i++ {
+		rows = append(rows, newSizedChild(list, math.NaN(), 40))
+	}
+	calculateLayout(t, list)
+
+	assertLayout(t, rows[2], 5, 85, 90, 40)
+	if w, h := GetLayoutContentWidth(list), GetLayoutContentHeight(list); w != 100 || h != 130 {
+		t.Errorf("content size = %v, %v, want 100, 130", w, h)
+	}
+	if !GetLayoutHadOverflow(list) {
+		t.Error("GetLayoutHadOverflow = false, want true")
+	}
+
+	RemoveChild(list, rows[2])
+	calculateLayout(t, list)
+	// The content size is never smaller than the padding box.
+	if h := GetLayoutContentHeight(list); h != 100 {
+		t.Errorf("content height = %v, want 100", h)
+	}
+	if GetLayoutHadOverflow(list) {
+		t.Error("GetLayoutHadOverflow = true after the content fits")
+	}
+}
+
+func TestScrollDoesNotConstrainMeasuredChildren(t *testing.T) {
+	for _, test := range []struct {
+		overflow Overflow
+		mode     MeasureMode
+		height   float64
+	}{
+		{OverflowVisible, MeasureModeAtmost, 50},
+		{OverflowHidden, MeasureModeAtmost, 50},
+		{OverflowScroll, MeasureModeUndefined, 200},
+	} {
+		root := NewNode()
+		SetOverflow(root, test.overflow)
+		SetWidth(root, 100)
+		SetHeight(root, 50)
+		var mode MeasureMode = -1
+		text := NewNode()
+		SetMeasureFunc(text, func(node *Node, width float64, widthMode MeasureMode, height float64, heightMode MeasureMode) Size {
+			mode = heightMode
+			if heightMode == MeasureModeAtmost {
+				return Size{width: width, height: math.Min(200, height)}
+			}
+			return Size{width: width, height: 200}
+		})
+		InsertChild(root, text, 0)
+		calculateLayout(t, root)
+		if mode != test.mode {
+			t.Errorf("overflow %v: height measured with mode %v, want %v", test.overflow, mode, test.mode)
+		}
+		if GetLayoutHeight(text) != test.height {
+			t.Errorf("overflow %v: height = %v, want %v", test.overflow, GetLayoutHeight(text), test.height)
+		}
+	}
+}
+
+func TestGetLayoutBox(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	child := newSizedChild(root, 50, 40)
+	SetMargin(child, EdgeTop, 1)
+	SetMargin(child, EdgeStart, 2)
+	SetBorder(child, EdgeAll, 3)
+	SetBorder(child, EdgeEnd, 4)
+	SetPaddingPercent(child, EdgeBottom, 10)
+	calculateLayout(t, root)
+
+	want := LayoutBox{
+		Left: 2, Top: 1, Width: 50, Height: 40,
+		Margin:  LayoutEdges{Left: 2, Top: 1},
+		Border:  LayoutEdges{Left: 3, Top: 3, Right: 4, Bottom: 3},
+		Padding: LayoutEdges{Bottom: 10},
+	}
+	if got := GetLayoutBox(child); got != want {
+		t.Errorf("GetLayoutBox = %+v, want %+v", got, want)
+	}
+	if _, err := GetLayoutBorder(child, EdgeAll); err == nil {
+		t.Error("GetLayoutBorder(EdgeAll) succeeded")
+	}
+
+	if err := CalculateLayout(root, math.NaN(), math.NaN(), DirectionRTL); err != nil {
+		t.Fatal(err)
+	}
+	want.Left = 48
+	want.Margin = LayoutEdges{Top: 1, Right: 2}
+	want.Border = LayoutEdges{Left: 4, Top: 3, Right: 3, Bottom: 3}
+	if got := GetLayoutBox(child); got != want {
+		t.Errorf("GetLayoutBox under RTL = %+v, want %+v", got, want)
+	}
+	if border, _ := GetLayoutBorder(child, EdgeEnd); border != 4 {
+		t.Errorf("GetLayoutBorder(EdgeEnd) under RTL = %v, want 4", border)
+	}
+}
+
+func TestStyleLogicalEdgesFollowWritingMode(t *testing.T) {
+	node := NewNode()
+	SetWritingMode(node, WritingModeVerticalRL)
+	SetMargin(node, EdgeHorizontal, 3)
+	SetPadding(node, EdgeVertical, 4)
+
+	margin, err := GetMargin(node, EdgeBlockStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if margin.value != 3 {
+		t.Errorf("GetMargin(EdgeBlockStart) = %v, want 3", margin.value)
+	}
+	if margin, _ := GetMargin(node, EdgeStart); !math.IsNaN(margin.value) {
+		t.Errorf("GetMargin(EdgeStart) = %v, want NaN", margin.value)
+	}
+	if padding, _ := GetPadding(node, EdgeEnd); padding.value != 4 {
+		t.Errorf("GetPadding(EdgeEnd) = %v, want 4", padding.value)
+	}
+	if padding, _ := GetPadding(node, EdgeBlockEnd); !math.IsNaN(padding.value) {
+		t.Errorf("GetPadding(EdgeBlockEnd) = %v, want NaN", padding.value)
+	}
+
+	if err := CalculateLayout(node, 100, 100, DirectionLTR); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetLayoutMargin(node, EdgeBlockStart); got != 3 {
+		t.Errorf("GetLayoutMargin(EdgeBlockStart) = %v, want 3", got)
+	}
+}
+
+func TestWritingModes(t *testing.T) {
+	for _, test := range []struct {
+		writingMode   WritingMode
+		flexDirection FlexDirection
+		first, second [2]float64
+	}{
+		{WritingModeHorizontalTB, FlexDirectionRow, [2]float64{0, 0}, [2]float64{20, 0}},
+		{WritingModeVerticalRL, FlexDirectionRow, [2]float64{80, 0}, [2]float64{80, 10}},
+		{WritingModeVerticalRL, FlexDirectionColumn, [2]float64{80, 0}, [2]float64{60, 0}},
+		{WritingModeVerticalLR, FlexDirectionRow, [2]float64{0, 0}, [2]float64{0, 10}},
+		{WritingModeVerticalLR, FlexDirectionColumn, [2]float64{0, 0}, [2]float64{20, 0}},
+	} {
+		root := NewNode()
+		SetWritingMode(root, test.writingMode)
+		SetFlexDirection(root, test.flexDirection)
+		SetAlignItems(root, AlignFlexStart)
+		SetWidth(root, 100)
+		SetHeight(root, 100)
+		first := newSizedChild(root, 20, 10)
+		second := newSizedChild(root, 20, 10)
+		calculateLayout(t, root)
+		if GetWritingMode(root) != test.writingMode {
+			t.Errorf("GetWritingMode = %v, want %v", GetWritingMode(root), test.writingMode)
+		}
+		assertLayout(t, first, test.first[0], test.first[1], 20, 10)
+		assertLayout(t, second, test.second[0], test.second[1], 20, 10)
+	}
+}
+
+func TestBlockEdges(t *testing.T) {
+	root := NewNode()
+	SetWidth(root, 100)
+	SetHeight(root, 100)
+	SetAlignItems(root, AlignFlexStart)
+	horizontal := newSizedChild(root, 20, 20)
+	SetMargin(horizontal, EdgeBlockStart, 5)
+	SetMargin(horizontal, EdgeVertical, 1)
+	vertical := newSizedChild(root, 20, 20)
+	SetWritingMode(vertical, WritingModeVerticalRL)
+	SetMargin(vertical, EdgeBlockStart, 3)
+	SetPadding(vertical, EdgeBlockEnd, 4)
+	calculateLayout(t, root)
+
+	// The block start of a horizontal-tb node is its top and that of a
+	// vertical-rl node its right.
+	assertLayout(t, horizontal, 0, 5, 20, 20)
+	assertLayout(t, vertical, 0, 26, 20, 20)
+	for _, test := range []struct {
+		name string
+		get  func(*Node, Edge) (float64, error)
+		node *Node
+		edge Edge
+		want float64
+	}{
+		{"horizontal margin block start", GetLayoutMargin, horizontal, EdgeBlockStart, 5},
+		{"horizontal margin block end", GetLayoutMargin, horizontal, EdgeBlockEnd, 1},
+		{"vertical margin right", GetLayoutMargin, vertical, EdgeRight, 3},
+		{"vertical padding left", GetLayoutPadding, vertical, EdgeLeft, 4},
+		{"vertical padding block end", GetLayoutPadding, vertical, EdgeBlockEnd, 4},
+	} {
+		if got, err := test.get(test.node, test.edge); err != nil || got != test.want {
+			t.Errorf("%s = %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+}